@@ -0,0 +1,215 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/larashed/agent-go/config"
+	"github.com/larashed/agent-go/monitoring/sender"
+)
+
+// the admin API controls the agent, only its owner may connect
+const socketPermissions = 0600
+
+// Status describes a running agent
+type Status struct {
+	Version     string                         `json:"version"`
+	Commit      string                         `json:"commit"`
+	PID         int                            `json:"pid"`
+	StartedAt   string                         `json:"started_at"`
+	Uptime      string                         `json:"uptime"`
+	LogLevel    string                         `json:"log_level"`
+	Collectors  []string                       `json:"collectors"`
+	Buckets     map[string]int                 `json:"buckets"` // Items waiting to be sent per stream
+	Streams     map[string]sender.StreamStatus `json:"streams"`
+	LastError   string                         `json:"last_error"` // Most recent failure across streams
+	LastErrorAt string                         `json:"last_error_at"`
+	Sender      *sender.InternalMetrics        `json:"sender"`
+	Config      *config.Config                 `json:"config"` // Secrets are masked
+}
+
+// Agent is the running agent controlled through the admin socket
+type Agent interface {
+	Status() Status
+	// Flush sends queued metrics and queues a server metric collection, which is sent once it completes
+	Flush()
+	SetLogLevel(level string) error
+}
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the admin API over a unix socket
+type Server struct {
+	path   string
+	server *http.Server
+}
+
+// NewServer creates a new `Server` instance
+func NewServer(path string, agent Agent) *Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			respond(w, http.StatusMethodNotAllowed, errorResponse{"Use GET"})
+			return
+		}
+
+		respond(w, http.StatusOK, agent.Status())
+	})
+	mux.HandleFunc("/flush", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			respond(w, http.StatusMethodNotAllowed, errorResponse{"Use POST"})
+			return
+		}
+
+		agent.Flush()
+		respond(w, http.StatusOK, agent.Status())
+	})
+	mux.HandleFunc("/log-level", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			respond(w, http.StatusMethodNotAllowed, errorResponse{"Use POST"})
+			return
+		}
+
+		var req logLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+
+		if err := agent.SetLogLevel(req.Level); err != nil {
+			respond(w, http.StatusBadRequest, errorResponse{err.Error()})
+			return
+		}
+
+		respond(w, http.StatusOK, agent.Status())
+	})
+
+	return &Server{
+		path:   path,
+		server: &http.Server{Handler: mux},
+	}
+}
+
+// Start serving the admin API. After Stop(), the returned error is `http.ErrServerClosed`.
+func (s *Server) Start() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return errors.Wrap(err, "Failed to create admin socket directory")
+	}
+
+	// a previous agent may not have cleaned up
+	if _, err := os.Stat(s.path); err == nil {
+		if err := syscall.Unlink(s.path); err != nil {
+			return errors.Wrap(err, "Failed to delete existing admin socket")
+		}
+	}
+
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return errors.Wrapf(err, `Failed to open admin socket "%s"`, s.path)
+	}
+
+	if err := os.Chmod(s.path, socketPermissions); err != nil {
+		listener.Close()
+		return errors.Wrapf(err, `Failed to change permissions of "%s"`, s.path)
+	}
+
+	return s.server.Serve(listener)
+}
+
+// Stop the admin API
+func (s *Server) Stop() error {
+	return s.server.Close()
+}
+
+func respond(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// Client queries a running agent's admin socket
+type Client struct {
+	client http.Client
+}
+
+// NewClient creates a new `Client` instance
+func NewClient(path string) *Client {
+	return &Client{
+		client: http.Client{
+			// flushing waits for API calls
+			Timeout: time.Minute,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}
+}
+
+// Status returns the agent's status
+func (c *Client) Status() (*Status, error) {
+	return c.do(http.MethodGet, "/status", nil)
+}
+
+// Flush makes the agent send queued metrics right away.
+// The server metric collection it queues completes in the background and isn't part of the returned status.
+func (c *Client) Flush() (*Status, error) {
+	return c.do(http.MethodPost, "/flush", nil)
+}
+
+// SetLogLevel changes the agent's logging level
+func (c *Client) SetLogLevel(level string) (*Status, error) {
+	return c.do(http.MethodPost, "/log-level", logLevelRequest{level})
+}
+
+func (c *Client) do(method, path string, body interface{}) (*Status, error) {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+
+	// the host is ignored, requests are dialed to the socket
+	req, err := http.NewRequest(method, "http://agent"+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to reach the agent, is it running with --admin-socket?")
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		var e errorResponse
+		if err := json.NewDecoder(res.Body).Decode(&e); err != nil || len(e.Error) == 0 {
+			return nil, errors.Errorf("Agent returned %s", res.Status)
+		}
+
+		return nil, errors.New(e.Error)
+	}
+
+	status := &Status{}
+	if err := json.NewDecoder(res.Body).Decode(status); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode agent status")
+	}
+
+	return status, nil
+}
@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/config"
+)
+
+type agentStandIn struct {
+	logLevel string
+	flushes  int
+}
+
+func (a *agentStandIn) Status() Status {
+	return Status{
+		Version:  "1.0.0",
+		LogLevel: a.logLevel,
+		Buckets:  map[string]int{"app_metrics": a.flushes},
+		Config:   (&config.Config{AppKey: "secret"}).Masked(),
+	}
+}
+
+func (a *agentStandIn) Flush() {
+	a.flushes++
+}
+
+func (a *agentStandIn) SetLogLevel(level string) error {
+	if level != "debug" {
+		return errors.Errorf(`Unknown logging level "%s", use info, debug or trace`, level)
+	}
+	a.logLevel = level
+
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "admin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "larashed", "admin.sock")
+	// left behind by a previous agent
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, ioutil.WriteFile(path, nil, 0644))
+
+	agent := &agentStandIn{logLevel: "info"}
+	server := NewServer(path, agent)
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.Start()
+	}()
+
+	client := NewClient(path)
+	var status *Status
+	for i := 0; i < 50; i++ {
+		if status, err = client.Status(); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0.0", status.Version)
+	assert.Equal(t, "********", status.Config.AppKey)
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(socketPermissions), info.Mode().Perm())
+
+	status, err = client.Flush()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, status.Buckets["app_metrics"])
+
+	status, err = client.SetLogLevel("debug")
+	assert.NoError(t, err)
+	assert.Equal(t, "debug", status.LogLevel)
+
+	_, err = client.SetLogLevel("verbose")
+	assert.EqualError(t, err, `Unknown logging level "verbose", use info, debug or trace`)
+
+	_, err = client.do(http.MethodPost, "/status", nil)
+	assert.EqualError(t, err, "Use GET")
+
+	assert.NoError(t, server.Stop())
+	assert.Equal(t, http.ErrServerClosed, <-stopped)
+
+	_, err = client.Status()
+	assert.Error(t, err)
+}
@@ -17,6 +17,7 @@ import (
 type Api interface { //nolint:golint
 	SendServerMetrics(data string) (*Response, error)
 	SendAppMetrics(data string) (*Response, error)
+	SendContainerEvents(data string) (*Response, error)
 	//SendDeployment(data string) (*Response, error)
 }
 
@@ -52,14 +53,35 @@ func (c *Client) SendAppMetrics(data string) (*Response, error) {
 	return c.doRequest("POST", "agent/app/metrics", data)
 }
 
+// SendContainerEvents sends container lifecycle events to our API
+func (c *Client) SendContainerEvents(data string) (*Response, error) {
+	return c.doRequest("POST", "agent/server/events", data)
+}
+
+// Ping makes an authenticated request which records nothing and returns the API's clock
+func (c *Client) Ping() (time.Time, error) {
+	_, header, err := c.request("GET", "agent/ping", "")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return http.ParseTime(header.Get("Date"))
+}
+
 func (c *Client) doRequest(method, url string, data string) (*Response, error) {
+	response, _, err := c.request(method, url, data)
+
+	return response, err
+}
+
+func (c *Client) request(method, url string, data string) (*Response, http.Header, error) {
 	req, err := http.NewRequest(
 		method,
 		strings.TrimRight(c.config.ApiUrl, "/")+"/v1/"+url,
 		bytes.NewBuffer([]byte(data)),
 	)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	req.Header.Set("User-Agent", "Larashed/GoAgent "+config.GitTag)
@@ -71,23 +93,24 @@ func (c *Client) doRequest(method, url string, data string) (*Response, error) {
 
 	res, err := c.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer res.Body.Close()
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	response := &Response{}
 	err = json.Unmarshal(body, &response)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if !response.Success {
-		return nil, errors.New(response.Message)
+		return nil, res.Header, errors.New(response.Message)
 	}
 
-	return response, nil
+	return response, res.Header, nil
 }
@@ -34,3 +34,12 @@ func (ac *MockAPIClient) SendAppMetrics(data string) (*Response, error) {
 	ac.appMetricCallsMade++
 	return nil, nil
 }
+
+// SendContainerEvents ...
+func (ac *MockAPIClient) SendContainerEvents(data string) (*Response, error) {
+	if ac.returnError {
+		return nil, errors.New("error")
+	}
+
+	return nil, nil
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"os"
+	"strconv"
 
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 	"github.com/shirou/gopsutil/host"
 	"github.com/urfave/cli/v2"
 
@@ -13,6 +16,60 @@ import (
 	socketserver "github.com/larashed/agent-go/server"
 )
 
+// runFlags configure the agent, commands inspecting it take the same flags as `run`
+var runFlags = []cli.Flag{
+	SocketTypeFlag,
+	SocketAddressFlag,
+	OldSocketAddressFlag,
+	SocketPermissionsFlag,
+	AdminSocketFlag,
+	ApiUrlFlag,
+	AppEnvFlag,
+	AppIDFlag,
+	AppKeyFlag,
+	ProcPathFlag,
+	SysPathFlag,
+	HostnameFlag,
+	LoggingLevelFlag,
+	CollectServerResourcesFlag,
+	CollectApplicationMetricsFlag,
+	CollectProcessesFlag,
+	ProcessLimitFlag,
+	RedactProcessCmdlineFlag,
+	CollectPHPFPMFlag,
+	PHPFPMEndpointFlag,
+	PHPFPMStatusPathFlag,
+	NginxStatusURLFlag,
+	ApacheStatusURLFlag,
+	MySQLDSNFlag,
+	RedisAddressFlag,
+	RedisPasswordFlag,
+	RedisDatabaseFlag,
+	RedisKeyPrefixFlag,
+	RedisQueueFlag,
+	CollectQueueWorkersFlag,
+	HorizonPrefixFlag,
+	MonitorSchedulerFlag,
+	SchedulerMissedWindowFlag,
+	LaravelPathFlag,
+	LogStateFileFlag,
+	LogSampleLimitFlag,
+	TLSEndpointFlag,
+	TLSCertificateFlag,
+	CollectPackageUpdatesFlag,
+	CollectServicesFlag,
+	SystemdUnitFlag,
+	VolumeSizeIntervalFlag,
+	VolumeSizeTimeoutFlag,
+	CollectContainersFlag,
+	PodmanSocketFlag,
+	ContainerdSocketFlag,
+	CgroupContainerStatsFlag,
+	KubernetesFlag,
+	KubeletURLFlag,
+	KubeletInsecureTLSFlag,
+}
+
 // App holds agent CLI app
 type App struct {
 	app *cli.App
@@ -30,7 +87,10 @@ func NewApp() *App {
 				Usage:   "Starts server monitoring & socket server",
 				Aliases: []string{"daemon"},
 				Action: func(c *cli.Context) error {
-					cfg := newConfig(c)
+					cfg, err := newConfig(c)
+					if err != nil {
+						return err
+					}
 					setEnvVariables(cfg)
 
 					// validate required flags and output error message with help
@@ -46,24 +106,65 @@ func NewApp() *App {
 					//apiClient := api.NewMockAPICClient(true)
 					apiClient := api.NewClient(cfg)
 
-					server := socketserver.NewServer(cfg.SocketType, cfg.SocketAddress)
+					server := socketserver.NewServer(cfg.SocketType, cfg.SocketAddress, cfg.SocketPermissions)
 
 					return commands.NewRunCommand(cfg, apiClient, server).Run()
 				},
+				Flags: runFlags,
+			},
+			{
+				Name:  "doctor",
+				Usage: "checks the configuration and everything the agent depends on",
+				Action: func(c *cli.Context) error {
+					cfg, err := newConfig(c)
+					if err != nil {
+						return cli.Exit(err, 1)
+					}
+					setEnvVariables(cfg)
+					// collectors log to stdout, keep the report readable
+					log.Bootstrap(zerolog.Disabled)
+
+					err = commands.NewDoctorCommand(cfg, api.NewClient(cfg)).Run(c.Bool(JSONFlagName))
+					if err != nil {
+						// exit without printing to stdout, which holds the report
+						return cli.Exit(err, 1)
+					}
+
+					return nil
+				},
+				Flags: append([]cli.Flag{JSONFlag}, runFlags...),
+			},
+			{
+				Name:  "collect",
+				Usage: "prints server metrics collected once, without sending them",
+				Action: func(c *cli.Context) error {
+					cfg, err := newConfig(c)
+					if err != nil {
+						return cli.Exit(err, 1)
+					}
+					setEnvVariables(cfg)
+					// collectors log to stdout, keep the output parseable
+					log.Bootstrap(zerolog.Disabled)
+
+					return commands.NewCollectCommand(cfg).Run(c.Bool(CompactFlagName), c.Duration(SampleIntervalFlagName))
+				},
+				Flags: append([]cli.Flag{CompactFlag, SampleIntervalFlag}, runFlags...),
+			},
+			{
+				Name:  "status",
+				Usage: "prints the status of the running agent and controls it",
+				Action: func(c *cli.Context) error {
+					return commands.NewStatusCommand(c.String(AdminSocketFlagName)).Run(
+						c.Bool(JSONFlagName),
+						c.Bool(FlushFlagName),
+						c.String(SetLogLevelFlagName),
+					)
+				},
 				Flags: []cli.Flag{
-					SocketTypeFlag,
-					SocketAddressFlag,
-					OldSocketAddressFlag,
-					ApiUrlFlag,
-					AppEnvFlag,
-					AppIDFlag,
-					AppKeyFlag,
-					ProcPathFlag,
-					SysPathFlag,
-					HostnameFlag,
-					LoggingLevelFlag,
-					CollectServerResourcesFlag,
-					CollectApplicationMetricsFlag,
+					AdminSocketFlag,
+					JSONFlag,
+					FlushFlag,
+					SetLogLevelFlag,
 				},
 			},
 			{
@@ -89,7 +190,7 @@ func (a *App) Run() error {
 	return a.app.Run(os.Args)
 }
 
-func newConfig(c *cli.Context) *config.Config {
+func newConfig(c *cli.Context) (*config.Config, error) {
 	cfg := &config.Config{
 		ApiUrl: c.String(ApiUrlFlagName),
 
@@ -108,14 +209,79 @@ func newConfig(c *cli.Context) *config.Config {
 		SocketAddress: c.String(SocketAddressFlagName),
 		SocketType:    c.String(SocketTypeFlagName),
 
+		AdminSocket: c.String(AdminSocketFlagName),
+
 		CollectServerResources: c.Bool(CollectServerResourcesFlagName),
 		CollectAppMetrics:      c.Bool(CollectApplicationMetricsFlagName),
+
+		CollectProcesses:     c.Bool(CollectProcessesFlagName),
+		ProcessLimit:         c.Int(ProcessLimitFlagName),
+		RedactProcessCmdline: c.Bool(RedactProcessCmdlineFlagName),
+
+		CollectPHPFPM:    c.Bool(CollectPHPFPMFlagName),
+		PHPFPMEndpoints:  c.StringSlice(PHPFPMEndpointFlagName),
+		PHPFPMStatusPath: c.String(PHPFPMStatusPathFlagName),
+
+		NginxStatusURLs:  c.StringSlice(NginxStatusURLFlagName),
+		ApacheStatusURLs: c.StringSlice(ApacheStatusURLFlagName),
+
+		MySQLDSN: c.String(MySQLDSNFlagName),
+
+		RedisAddress:   c.String(RedisAddressFlagName),
+		RedisPassword:  c.String(RedisPasswordFlagName),
+		RedisDatabase:  c.Int(RedisDatabaseFlagName),
+		RedisKeyPrefix: c.String(RedisKeyPrefixFlagName),
+		RedisQueues:    c.StringSlice(RedisQueueFlagName),
+
+		CollectQueueWorkers: c.Bool(CollectQueueWorkersFlagName),
+		HorizonPrefix:       c.String(HorizonPrefixFlagName),
+
+		MonitorScheduler:      c.Bool(MonitorSchedulerFlagName),
+		SchedulerMissedWindow: c.Duration(SchedulerMissedWindowFlagName),
+
+		LaravelPaths:   c.StringSlice(LaravelPathFlagName),
+		LogStateFile:   c.String(LogStateFileFlagName),
+		LogSampleLimit: c.Int(LogSampleLimitFlagName),
+
+		TLSEndpoints:        c.StringSlice(TLSEndpointFlagName),
+		TLSCertificateFiles: c.StringSlice(TLSCertificateFlagName),
+
+		CollectPackageUpdates: c.Bool(CollectPackageUpdatesFlagName),
+
+		CollectServices: c.Bool(CollectServicesFlagName),
+		SystemdUnits:    c.StringSlice(SystemdUnitFlagName),
+
+		VolumeSizeInterval: c.Duration(VolumeSizeIntervalFlagName),
+		VolumeSizeTimeout:  c.Duration(VolumeSizeTimeoutFlagName),
+
+		CollectContainers: c.Bool(CollectContainersFlagName),
+		PodmanSocket:      c.String(PodmanSocketFlagName),
+		ContainerdSocket:  c.String(ContainerdSocketFlagName),
+
+		CgroupContainerStats: c.Bool(CgroupContainerStatsFlagName),
+
+		Kubernetes:         c.Bool(KubernetesFlagName),
+		KubeletURL:         c.String(KubeletURLFlagName),
+		KubeletInsecureTLS: c.Bool(KubeletInsecureTLSFlagName),
+	}
+
+	if value := c.String(SocketPermissionsFlagName); len(value) > 0 {
+		permissions, err := strconv.ParseUint(value, 8, 32)
+		if err != nil || permissions > 0777 {
+			return nil, errors.Errorf(`Invalid --%s "%s", use an octal file mode such as 0666`, SocketPermissionsFlagName, value)
+		}
+		cfg.SocketPermissions = os.FileMode(permissions)
 	}
 
 	if len(cfg.SocketAddress) == 0 {
 		cfg.SocketAddress = c.String(SocketAddressOldFlagName)
 	}
 
+	// pods are named after themselves, the node name is passed through the downward API
+	if len(cfg.Hostname) == 0 && cfg.Kubernetes {
+		cfg.Hostname = os.Getenv("NODE_NAME")
+	}
+
 	if len(cfg.Hostname) == 0 {
 		hostname, err := host.Info()
 		if err == nil {
@@ -123,7 +289,7 @@ func newConfig(c *cli.Context) *config.Config {
 		}
 	}
 
-	return cfg
+	return cfg, nil
 }
 
 func validateConfig(value, flag string) bool {
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/larashed/agent-go/config"
+	"github.com/larashed/agent-go/monitoring/buckets"
+	"github.com/larashed/agent-go/monitoring/collectors"
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// CollectCommand prints a single server metric collection without sending it
+type CollectCommand struct {
+	config *config.Config
+}
+
+// NewCollectCommand creates an instance of `CollectCommand`
+func NewCollectCommand(cfg *config.Config) *CollectCommand {
+	// both need a running agent: schedule:run is watched over time and volumes are measured in the background
+	cfg.MonitorScheduler = false
+	cfg.VolumeSizeInterval = 0
+
+	return &CollectCommand{
+		config: cfg,
+	}
+}
+
+// Run collects server metrics and prints them as JSON. With a `sampleInterval`, the first collection
+// only primes values measured between collections and the second one is printed.
+func (c *CollectCommand) Run(compact bool, sampleInterval time.Duration) error {
+	collector := collectors.NewServerMetricCollector(buckets.NewServerMetricBucket(), sampleInterval, c.config)
+	defer collector.Close()
+
+	metric, err := collector.Collect()
+	if err == nil && sampleInterval > 0 {
+		time.Sleep(sampleInterval)
+		metric, err = collector.Collect()
+	}
+	if err != nil {
+		return errors.Wrap(err, "Failed to collect server metrics")
+	}
+
+	fmt.Println(formatServerMetric(metric, compact))
+
+	return nil
+}
+
+func formatServerMetric(metric *metrics.ServerMetric, compact bool) string {
+	if compact {
+		return metric.String()
+	}
+
+	metric.CreatedAtFormatted = metric.CreatedAt.Format(time.RFC3339)
+	j, _ := json.MarshalIndent(metric, "", "  ")
+
+	return string(j)
+}
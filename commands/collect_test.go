@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/config"
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+func TestFormatServerMetric(t *testing.T) {
+	metric := &metrics.ServerMetric{
+		Hostname:  "web-1",
+		CreatedAt: time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	compact := formatServerMetric(metric, true)
+	assert.False(t, strings.Contains(compact, "\n"))
+	assert.Contains(t, compact, `"hostname":"web-1"`)
+	assert.Contains(t, compact, `"created_at":"2021-01-02T03:04:05Z"`)
+
+	pretty := formatServerMetric(metric, false)
+	assert.Contains(t, pretty, "\n  \"hostname\": \"web-1\",")
+	assert.Contains(t, pretty, `"created_at": "2021-01-02T03:04:05Z"`)
+}
+
+func TestNewCollectCommand(t *testing.T) {
+	cmd := NewCollectCommand(&config.Config{MonitorScheduler: true, VolumeSizeInterval: time.Minute})
+
+	assert.False(t, cmd.config.MonitorScheduler)
+	assert.Equal(t, time.Duration(0), cmd.config.VolumeSizeInterval)
+}
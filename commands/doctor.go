@@ -0,0 +1,409 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+
+	"github.com/larashed/agent-go/config"
+	"github.com/larashed/agent-go/monitoring/collectors"
+)
+
+const (
+	doctorPass = "pass"
+	doctorWarn = "warn" // Passed with a caveat
+	doctorFail = "fail"
+	doctorSkip = "skip" // Doesn't apply to this agent
+
+	// access(2) mode checking write permission
+	accessWrite = 0x2
+
+	doctorTimeout = 5 * time.Second
+	// the API's clock is read from the Date header, which has a one second resolution
+	clockSkewLimit = 30 * time.Second
+)
+
+// DoctorCheck is the outcome of a single diagnostic
+type DoctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// DoctorReport holds the outcome of every diagnostic
+type DoctorReport struct {
+	Passed bool          `json:"passed"`
+	Checks []DoctorCheck `json:"checks"`
+}
+
+// pinger is the part of the API client used by the doctor
+type pinger interface {
+	Ping() (time.Time, error)
+}
+
+// DoctorCommand checks everything the agent depends on
+type DoctorCommand struct {
+	config *config.Config
+	api    pinger
+}
+
+// NewDoctorCommand creates an instance of `DoctorCommand`
+func NewDoctorCommand(cfg *config.Config, apiClient pinger) *DoctorCommand {
+	return &DoctorCommand{
+		config: cfg,
+		api:    apiClient,
+	}
+}
+
+// Run prints a pass/fail report and returns an error when a check failed
+func (d *DoctorCommand) Run(isJSON bool) error {
+	report := d.Report()
+
+	if isJSON {
+		j, _ := json.Marshal(report)
+		fmt.Println(string(j))
+	} else {
+		for _, check := range report.Checks {
+			fmt.Printf("[%s] %-11s %s\n", strings.ToUpper(check.Status), check.Name, check.Message)
+		}
+	}
+
+	if !report.Passed {
+		return errors.New("Some checks failed")
+	}
+
+	return nil
+}
+
+// Report runs every check
+func (d *DoctorCommand) Report() DoctorReport {
+	configCheck := d.checkConfig()
+	apiCheck, skewCheck := d.checkAPI(configCheck.Status == doctorFail)
+
+	report := DoctorReport{
+		Passed: true,
+		Checks: []DoctorCheck{
+			configCheck,
+			apiCheck,
+			skewCheck,
+			d.checkSocket(),
+			d.checkProcfs(),
+			d.checkSysfs(),
+			d.checkDocker(),
+			d.checkSystemd(),
+			d.checkPHP(),
+		},
+	}
+
+	for _, check := range report.Checks {
+		if check.Status == doctorFail {
+			report.Passed = false
+		}
+	}
+
+	return report
+}
+
+func (d *DoctorCommand) checkConfig() DoctorCheck {
+	check := DoctorCheck{Name: "config"}
+
+	var missing []string
+	for _, option := range [][2]string{
+		{"--socket-address", d.config.SocketAddress},
+		{"--app-id", d.config.AppId},
+		{"--app-key", d.config.AppKey},
+		{"--env", d.config.AppEnvironment},
+	} {
+		if len(option[1]) == 0 {
+			missing = append(missing, option[0])
+		}
+	}
+
+	apiURL, err := url.Parse(d.config.ApiUrl)
+	switch {
+	case len(missing) > 0:
+		check.Status = doctorFail
+		check.Message = "Missing " + strings.Join(missing, ", ")
+	case d.config.SocketType != "unix" && d.config.SocketType != "tcp":
+		check.Status = doctorFail
+		check.Message = fmt.Sprintf(`Unknown socket type "%s", use unix or tcp`, d.config.SocketType)
+	case err != nil || (apiURL.Scheme != "http" && apiURL.Scheme != "https") || len(apiURL.Host) == 0:
+		check.Status = doctorFail
+		check.Message = fmt.Sprintf(`Invalid API URL "%s"`, d.config.ApiUrl)
+	default:
+		check.Status = doctorPass
+		check.Message = "Required options are set"
+	}
+
+	return check
+}
+
+// checkAPI verifies credentials and compares the API's clock with the local one
+func (d *DoctorCommand) checkAPI(invalidConfig bool) (DoctorCheck, DoctorCheck) {
+	apiCheck := DoctorCheck{Name: "api"}
+	skewCheck := DoctorCheck{Name: "clock"}
+
+	if invalidConfig {
+		apiCheck.Status, apiCheck.Message = doctorSkip, "Configuration is invalid"
+		skewCheck.Status, skewCheck.Message = doctorSkip, "API wasn't reached"
+
+		return apiCheck, skewCheck
+	}
+
+	sentAt := time.Now()
+	serverTime, err := d.api.Ping()
+	receivedAt := time.Now()
+	if err != nil {
+		apiCheck.Status, apiCheck.Message = doctorFail, fmt.Sprintf("%s: %s", d.config.ApiUrl, err)
+		skewCheck.Status, skewCheck.Message = doctorSkip, "API wasn't reached"
+
+		return apiCheck, skewCheck
+	}
+
+	apiCheck.Status = doctorPass
+	apiCheck.Message = fmt.Sprintf("Authenticated with %s in %s", d.config.ApiUrl, receivedAt.Sub(sentAt).Round(time.Millisecond))
+
+	skew := sentAt.Add(receivedAt.Sub(sentAt) / 2).Sub(serverTime).Round(time.Second)
+	skewCheck.Status = doctorPass
+	if skew > clockSkewLimit || skew < -clockSkewLimit {
+		skewCheck.Status = doctorFail
+	}
+	skewCheck.Message = fmt.Sprintf("Local clock is %s off the API's, at most %s is allowed", skew, clockSkewLimit)
+
+	return apiCheck, skewCheck
+}
+
+// checkSocket verifies the agent can listen on its socket and that the application can connect to it
+func (d *DoctorCommand) checkSocket() DoctorCheck {
+	check := DoctorCheck{Name: "socket"}
+	if len(d.config.SocketAddress) == 0 {
+		check.Status, check.Message = doctorSkip, "No socket address"
+
+		return check
+	}
+
+	if d.config.SocketType == "tcp" {
+		listener, err := net.Listen("tcp", d.config.SocketAddress)
+		if err == nil {
+			listener.Close()
+			check.Status, check.Message = doctorPass, fmt.Sprintf("Can listen on %s", d.config.SocketAddress)
+		} else if socketInUse("tcp", d.config.SocketAddress) {
+			check.Status, check.Message = doctorWarn, fmt.Sprintf("%s is in use, the agent may already be running", d.config.SocketAddress)
+		} else {
+			check.Status, check.Message = doctorFail, err.Error()
+		}
+
+		return check
+	}
+
+	dir := filepath.Dir(d.config.SocketAddress)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		check.Status, check.Message = doctorFail, fmt.Sprintf("Directory %s doesn't exist", dir)
+
+		return check
+	}
+	if err := syscall.Access(dir, accessWrite); err != nil {
+		check.Status, check.Message = doctorFail, fmt.Sprintf("Directory %s isn't writable by uid %d", dir, os.Getuid())
+
+		return check
+	}
+
+	info, err := os.Stat(d.config.SocketAddress)
+	if err != nil {
+		check.Status, check.Message = doctorPass, fmt.Sprintf("Can create %s", d.config.SocketAddress)
+		if d.config.SocketPermissions == 0 && os.Getuid() == 0 {
+			check.Status = doctorWarn
+			check.Message += ", only root will be able to connect unless --socket-permissions is set"
+		}
+
+		return check
+	}
+
+	owner := ""
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		owner = fmt.Sprintf(", owned by %d:%d", stat.Uid, stat.Gid)
+	}
+	check.Message = fmt.Sprintf("%s exists with mode %s%s", d.config.SocketAddress, info.Mode().Perm(), owner)
+
+	switch {
+	case info.Mode()&os.ModeSocket == 0:
+		check.Status = doctorFail
+		check.Message += ", it isn't a socket"
+	case info.Mode().Perm()&0002 == 0:
+		// connecting to a unix socket requires write permission
+		check.Status = doctorWarn
+		check.Message += ", other users can't connect, see --socket-permissions"
+	case socketInUse("unix", d.config.SocketAddress):
+		check.Status = doctorWarn
+		check.Message += ", the agent may already be running"
+	default:
+		check.Status = doctorPass
+	}
+
+	return check
+}
+
+func (d *DoctorCommand) checkProcfs() DoctorCheck {
+	check := DoctorCheck{Name: "procfs"}
+
+	for _, file := range []string{"stat", "meminfo", "loadavg"} {
+		path := filepath.Join(d.config.PathProcfs, file)
+		if _, err := os.Stat(path); err != nil {
+			check.Status, check.Message = doctorFail, fmt.Sprintf("Can't read %s, check --path-proc", path)
+			if runtime.GOOS != "linux" {
+				check.Status = doctorSkip
+				check.Message = "procfs is only available on Linux"
+			}
+
+			return check
+		}
+	}
+
+	check.Status, check.Message = doctorPass, fmt.Sprintf("%s is readable", d.config.PathProcfs)
+
+	return check
+}
+
+func (d *DoctorCommand) checkSysfs() DoctorCheck {
+	check := DoctorCheck{Name: "sysfs"}
+
+	if runtime.GOOS != "linux" {
+		check.Status, check.Message = doctorSkip, "sysfs is only available on Linux"
+
+		return check
+	}
+
+	if _, err := os.Stat(d.config.PathSysfs); err != nil {
+		check.Status, check.Message = doctorFail, fmt.Sprintf("Can't read %s, check --path-sys", d.config.PathSysfs)
+
+		return check
+	}
+
+	cgroup := filepath.Join(d.config.PathSysfs, "fs", "cgroup")
+	if _, err := os.Stat(cgroup); err != nil {
+		check.Status, check.Message = doctorWarn, fmt.Sprintf("%s is missing, container usage can't be read from cgroups", cgroup)
+
+		return check
+	}
+
+	check.Status, check.Message = doctorPass, fmt.Sprintf("%s is readable", d.config.PathSysfs)
+
+	return check
+}
+
+func (d *DoctorCommand) checkDocker() DoctorCheck {
+	check := DoctorCheck{Name: "docker"}
+
+	if d.config.InDocker && !d.config.Kubernetes {
+		check.Status, check.Message = doctorSkip, "Containers aren't collected from within a container"
+
+		return check
+	}
+
+	host := os.Getenv("DOCKER_HOST")
+	if len(host) == 0 {
+		host = client.DefaultDockerHost
+	}
+
+	socket := strings.TrimPrefix(host, "unix://")
+	if socket != host {
+		if _, err := os.Stat(socket); err != nil {
+			check.Status, check.Message = doctorSkip, fmt.Sprintf("%s doesn't exist", socket)
+
+			return check
+		}
+	}
+
+	apiClient, err := client.NewClientWithOpts(client.WithHost(host), client.WithAPIVersionNegotiation())
+	if err != nil {
+		check.Status, check.Message = doctorFail, err.Error()
+
+		return check
+	}
+	defer apiClient.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	version, err := apiClient.ServerVersion(ctx)
+	if err != nil {
+		check.Status, check.Message = doctorFail, err.Error()
+		if strings.Contains(err.Error(), "permission denied") {
+			check.Message = fmt.Sprintf("Permission denied on %s, add the agent's user to the docker group", socket)
+		}
+
+		return check
+	}
+
+	check.Status, check.Message = doctorPass, fmt.Sprintf("Docker %s at %s", version.Version, host)
+
+	return check
+}
+
+// checkSystemd verifies the D-Bus access used to collect services
+func (d *DoctorCommand) checkSystemd() DoctorCheck {
+	check := DoctorCheck{Name: "systemd"}
+
+	if d.config.InDocker || runtime.GOOS != "linux" {
+		check.Status, check.Message = doctorSkip, "Services are only collected on a Linux host"
+
+		return check
+	}
+
+	// sd_booted(3)
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		check.Status, check.Message = doctorSkip, "systemd isn't running"
+
+		return check
+	}
+
+	serviceCollector := collectors.NewServiceCollector(d.config.SystemdUnits)
+	defer serviceCollector.Close()
+
+	services, err := serviceCollector.Collect()
+	if err != nil {
+		check.Status, check.Message = doctorFail, errors.Cause(err).Error()
+
+		return check
+	}
+
+	check.Status, check.Message = doctorPass, fmt.Sprintf("%d services visible over D-Bus", len(services))
+
+	return check
+}
+
+func (d *DoctorCommand) checkPHP() DoctorCheck {
+	check := DoctorCheck{Name: "php"}
+
+	version, err := collectors.PHPVersion()
+	if err != nil || len(version) == 0 {
+		check.Status, check.Message = doctorWarn, "No php binary in PATH, the PHP version won't be reported"
+
+		return check
+	}
+
+	check.Status, check.Message = doctorPass, "PHP "+version
+
+	return check
+}
+
+func socketInUse(network, address string) bool {
+	conn, err := net.DialTimeout(network, address, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+
+	return true
+}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/config"
+)
+
+type apiStandIn struct {
+	serverTime time.Time
+	err        error
+}
+
+func (a *apiStandIn) Ping() (time.Time, error) {
+	return a.serverTime, a.err
+}
+
+func doctorConfig(socketAddress string) *config.Config {
+	return &config.Config{
+		ApiUrl:         "https://api.larashed.com/",
+		AppEnvironment: "production",
+		AppId:          "id",
+		AppKey:         "key",
+		SocketType:     "unix",
+		SocketAddress:  socketAddress,
+		PathProcfs:     "/proc",
+		PathSysfs:      "/sys",
+	}
+}
+
+func TestDoctorConfig(t *testing.T) {
+	cfg := doctorConfig("/tmp/larashed.sock")
+	assert.Equal(t, doctorPass, NewDoctorCommand(cfg, nil).checkConfig().Status)
+
+	cfg.AppKey = ""
+	cfg.AppEnvironment = ""
+	check := NewDoctorCommand(cfg, nil).checkConfig()
+	assert.Equal(t, doctorFail, check.Status)
+	assert.Equal(t, "Missing --app-key, --env", check.Message)
+
+	cfg = doctorConfig("/tmp/larashed.sock")
+	cfg.ApiUrl = "api.larashed.com"
+	assert.Equal(t, doctorFail, NewDoctorCommand(cfg, nil).checkConfig().Status)
+}
+
+func TestDoctorAPI(t *testing.T) {
+	cfg := doctorConfig("/tmp/larashed.sock")
+
+	api, clock := NewDoctorCommand(cfg, &apiStandIn{serverTime: time.Now()}).checkAPI(false)
+	assert.Equal(t, doctorPass, api.Status)
+	assert.Equal(t, doctorPass, clock.Status)
+
+	api, clock = NewDoctorCommand(cfg, &apiStandIn{serverTime: time.Now().Add(-2 * time.Minute)}).checkAPI(false)
+	assert.Equal(t, doctorPass, api.Status)
+	assert.Equal(t, doctorFail, clock.Status)
+	assert.Contains(t, clock.Message, "2m0s")
+
+	api, clock = NewDoctorCommand(cfg, &apiStandIn{err: errors.New("Unauthorized")}).checkAPI(false)
+	assert.Equal(t, doctorFail, api.Status)
+	assert.Contains(t, api.Message, "Unauthorized")
+	assert.Equal(t, doctorSkip, clock.Status)
+
+	api, _ = NewDoctorCommand(cfg, nil).checkAPI(true)
+	assert.Equal(t, doctorSkip, api.Status)
+}
+
+func TestDoctorSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "socket")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	check := NewDoctorCommand(doctorConfig(filepath.Join(dir, "missing", "agent.sock")), nil).checkSocket()
+	assert.Equal(t, doctorFail, check.Status)
+
+	address := filepath.Join(dir, "agent.sock")
+	cfg := doctorConfig(address)
+	cfg.SocketPermissions = 0666
+	check = NewDoctorCommand(cfg, nil).checkSocket()
+	assert.Equal(t, doctorPass, check.Status)
+
+	listener, err := net.Listen("unix", address)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	assert.NoError(t, os.Chmod(address, 0755))
+	check = NewDoctorCommand(cfg, nil).checkSocket()
+	assert.Equal(t, doctorWarn, check.Status)
+	assert.Contains(t, check.Message, "other users can't connect")
+
+	assert.NoError(t, os.Chmod(address, 0666))
+	check = NewDoctorCommand(cfg, nil).checkSocket()
+	assert.Equal(t, doctorWarn, check.Status)
+	assert.Contains(t, check.Message, "already be running")
+
+	file := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(file, nil, 0666))
+	check = NewDoctorCommand(doctorConfig(file), nil).checkSocket()
+	assert.Equal(t, doctorFail, check.Status)
+}
+
+func TestDoctorProcfs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := doctorConfig("")
+	cfg.PathProcfs = dir
+	assert.NotEqual(t, doctorPass, NewDoctorCommand(cfg, nil).checkProcfs().Status)
+
+	for _, file := range []string{"stat", "meminfo", "loadavg"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, file), nil, 0644))
+	}
+	assert.Equal(t, doctorPass, NewDoctorCommand(cfg, nil).checkProcfs().Status)
+}
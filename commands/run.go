@@ -1,18 +1,23 @@
 package commands
 
 import (
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/docker/go-units"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
 	"github.com/pkg/errors"
 
+	"github.com/larashed/agent-go/admin"
 	"github.com/larashed/agent-go/api"
 	"github.com/larashed/agent-go/config"
+	agentlog "github.com/larashed/agent-go/log"
 	"github.com/larashed/agent-go/monitoring"
 	"github.com/larashed/agent-go/monitoring/buckets"
 	"github.com/larashed/agent-go/monitoring/collectors"
@@ -31,7 +36,18 @@ type RunCommand struct {
 	stopCollectorServer chan struct{}
 	stopSenderApp       chan struct{}
 	stopSenderServer    chan struct{}
+	stopLogTailer       chan struct{}
 	errorChan           chan error
+
+	dockerEventWatcher *collectors.DockerEventWatcher
+	metricSender       *sender.Sender
+
+	// reported through the admin socket
+	startedAt             time.Time
+	serverMetricCollector *collectors.ServerMetricCollector
+	appMetricBucket       *buckets.AppMetricBucket
+	containerEventBucket  *buckets.ContainerEventBucket
+	adminServer           *admin.Server
 }
 
 // NewRunCommand creates an instance of `RunCommand`
@@ -45,14 +61,17 @@ func NewRunCommand(cfg *config.Config, apiClient api.Api, socketServer *socketse
 		stopCollectorServer: make(chan struct{}),
 		stopSenderApp:       make(chan struct{}),
 		stopSenderServer:    make(chan struct{}),
+		stopLogTailer:       make(chan struct{}),
 		errorChan:           make(chan error),
+
+		startedAt: time.Now(),
 	}
 }
 
 // Run starts the agent
 func (d *RunCommand) Run() error {
 	log.Info().Msgf("Starting agent v%s", config.GitTag)
-	log.Trace().Msgf("Config: %s", d.config.String())
+	log.Trace().Msgf("Config: %s", d.config.Masked().String())
 	log.Info().Msgf("Agent running with PID %d", os.Getpid())
 
 	cfg := &monitoring.Config{
@@ -62,35 +81,61 @@ func (d *RunCommand) Run() error {
 		AppMetricOverflowLimitBytes:     50 * units.MiB,
 		ServerMetricSendInterval:        30 * time.Second,
 		AppMetricSleepDurationOnFailure: 4 * time.Second,
+		LogReportInterval:               30 * time.Second,
+		ContainerEventSendInterval:      5 * time.Second,
 	}
 
 	appMetricBucket := buckets.NewAppMetricBucket()
 	serverMetricBucket := buckets.NewServerMetricBucket()
+	containerEventBucket := buckets.NewContainerEventBucket(1000)
 
 	serverMetricCollector := collectors.NewServerMetricCollector(
 		serverMetricBucket,
 		cfg.ServerMetricSendInterval,
-		d.config.Hostname,
-		d.config.InDocker,
+		d.config,
 	)
 
-	metricSender := sender.NewSender(d.api, appMetricBucket, serverMetricBucket, cfg, false)
+	// internal metrics are reported by the status command
+	metricSender := sender.NewSender(d.api, appMetricBucket, serverMetricBucket, containerEventBucket, cfg, true)
+	d.metricSender = metricSender
+	d.serverMetricCollector = serverMetricCollector
+	d.appMetricBucket = appMetricBucket
+	d.containerEventBucket = containerEventBucket
 
 	if d.config.CollectServerResources {
 		go d.runServerMetricCollector(serverMetricCollector)
 		go d.runServerMetricSender(metricSender)
+
+		if !d.config.InDocker && d.config.CollectContainers {
+			d.runDockerEventWatcher(containerEventBucket)
+		}
 	} else {
 		log.Info().Msg("[Disabled] Server resource collection")
 	}
 
 	if d.config.CollectAppMetrics {
-		go d.runSocketServer(appMetricBucket)
+		go d.runSocketServer(appMetricBucket, serverMetricCollector.SchedulerMonitor())
 		go d.runAppMetricSender(metricSender)
 		log.Info().Msgf("Socket address: %s://%s", d.config.SocketType, d.config.SocketAddress)
+
+		if d.tailsLogs() {
+			go d.runLogTailer(collectors.NewLogTailer(collectors.LogTailerConfig{
+				AppPaths:       d.config.LaravelPaths,
+				StateFile:      d.config.LogStateFile,
+				SampleLimit:    d.config.LogSampleLimit,
+				Environment:    d.config.AppEnvironment,
+				ReportInterval: cfg.LogReportInterval,
+			}, appMetricBucket))
+		}
 	} else {
 		log.Info().Msg("[Disabled] Application metric collection")
 	}
 
+	if len(d.config.AdminSocket) > 0 {
+		d.adminServer = admin.NewServer(d.config.AdminSocket, d)
+		go d.runAdminServer()
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 	select {
@@ -110,11 +155,28 @@ func (d *RunCommand) Shutdown() {
 	if d.config.CollectServerResources {
 		d.stopSenderServer <- struct{}{}
 		d.stopCollectorServer <- struct{}{}
+
+		if d.dockerEventWatcher != nil {
+			d.dockerEventWatcher.Stop()
+			d.metricSender.StopSendingContainerEvents()
+
+			log.Info().Msg("Stopped Docker event watcher")
+		}
 	}
 
 	if d.config.CollectAppMetrics {
 		d.stopSenderApp <- struct{}{}
 		d.stopSocketServer <- struct{}{}
+
+		if d.tailsLogs() {
+			d.stopLogTailer <- struct{}{}
+		}
+	}
+
+	if d.adminServer != nil {
+		if err := d.adminServer.Stop(); err != nil {
+			log.Info().Msgf("Error stopping admin server: %s", err)
+		}
 	}
 
 	time.Sleep(100 * time.Millisecond)
@@ -124,7 +186,7 @@ func (d *RunCommand) Shutdown() {
 	os.Exit(1)
 }
 
-func (d *RunCommand) runSocketServer(bucket *buckets.AppMetricBucket) {
+func (d *RunCommand) runSocketServer(bucket *buckets.AppMetricBucket, scheduler *collectors.SchedulerMonitor) {
 	go func() {
 		<-d.stopSocketServer
 		err := d.socketServer.Stop()
@@ -142,6 +204,10 @@ func (d *RunCommand) runSocketServer(bucket *buckets.AppMetricBucket) {
 			return
 		}
 
+		if scheduler != nil && socketserver.IsScheduleMessage(message) {
+			scheduler.Heartbeat(collectors.SchedulerSourceSocket, time.Now())
+		}
+
 		bucket.Add(metrics.NewAppMetric(message))
 	}
 
@@ -151,6 +217,22 @@ func (d *RunCommand) runSocketServer(bucket *buckets.AppMetricBucket) {
 	}
 }
 
+func (d *RunCommand) tailsLogs() bool {
+	return len(d.config.LaravelPaths) > 0
+}
+
+func (d *RunCommand) runLogTailer(tailer *collectors.LogTailer) {
+	go func() {
+		<-d.stopLogTailer
+		tailer.Stop()
+
+		log.Info().Msg("Stopped log tailer")
+	}()
+
+	log.Info().Msgf("Starting log tailer for %s", strings.Join(d.config.LaravelPaths, ", "))
+	tailer.Start()
+}
+
 func (d *RunCommand) runServerMetricCollector(serverMetricCollector *collectors.ServerMetricCollector) {
 	go func() {
 		<-d.stopCollectorServer
@@ -163,6 +245,20 @@ func (d *RunCommand) runServerMetricCollector(serverMetricCollector *collectors.
 	serverMetricCollector.Start()
 }
 
+func (d *RunCommand) runDockerEventWatcher(bucket *buckets.ContainerEventBucket) {
+	watcher, err := collectors.NewDockerEventWatcher(bucket, d.config.Hostname)
+	if err != nil {
+		log.Trace().Err(err).Msg("Docker event forwarding disabled")
+
+		return
+	}
+	d.dockerEventWatcher = watcher
+
+	log.Info().Msg("Starting Docker event watcher")
+	go watcher.Start()
+	d.metricSender.StartContainerEventSend()
+}
+
 func (d *RunCommand) runServerMetricSender(sender *sender.Sender) {
 	go func() {
 		<-d.stopSenderServer
@@ -186,3 +282,81 @@ func (d *RunCommand) runAppMetricSender(sender *sender.Sender) {
 	log.Info().Msg("Starting app metric sender")
 	sender.StartAppMetricSend()
 }
+
+// runAdminServer serves the status and control API, the agent keeps running without it
+func (d *RunCommand) runAdminServer() {
+	log.Info().Msgf("Admin socket: %s", d.config.AdminSocket)
+	if err := d.adminServer.Start(); err != nil && err != http.ErrServerClosed {
+		log.Error().Err(err).Msg("Admin socket disabled")
+	}
+}
+
+// Status reports the agent's state to the admin socket
+func (d *RunCommand) Status() admin.Status {
+	status := admin.Status{
+		Version:    config.GitTag,
+		Commit:     config.GitCommit,
+		PID:        os.Getpid(),
+		StartedAt:  d.startedAt.Format(time.RFC3339),
+		Uptime:     time.Since(d.startedAt).Round(time.Second).String(),
+		LogLevel:   zerolog.GlobalLevel().String(),
+		Collectors: d.collectors(),
+		Buckets: map[string]int{
+			sender.StreamAppMetrics:      d.appMetricBucket.Count(),
+			sender.StreamContainerEvents: d.containerEventBucket.Count(),
+		},
+		Streams: d.metricSender.Status(),
+		Sender:  d.metricSender.GetInternalMetrics(),
+		Config:  d.config.Masked(),
+	}
+
+	// RFC3339 times in the same zone sort as strings
+	for _, stream := range status.Streams {
+		if stream.LastErrorAt > status.LastErrorAt {
+			status.LastErrorAt = stream.LastErrorAt
+			status.LastError = stream.LastError
+		}
+	}
+
+	return status
+}
+
+// Flush sends queued metrics and queues a server metric collection, which is sent once it completes
+func (d *RunCommand) Flush() {
+	log.Info().Msg("Flushing queued metrics")
+
+	d.metricSender.Flush()
+	if d.config.CollectServerResources {
+		d.serverMetricCollector.CollectNow()
+	}
+}
+
+// SetLogLevel changes the logging level until the agent restarts
+func (d *RunCommand) SetLogLevel(level string) error {
+	if err := agentlog.SetLevel(level); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("Log level changed to %s", level)
+
+	return nil
+}
+
+func (d *RunCommand) collectors() []string {
+	var names []string
+	if d.config.CollectServerResources {
+		names = append(names, d.serverMetricCollector.Collectors()...)
+		if d.dockerEventWatcher != nil {
+			names = append(names, "docker-events")
+		}
+	}
+
+	if d.config.CollectAppMetrics {
+		names = append(names, "app-metrics")
+		if d.tailsLogs() {
+			names = append(names, "laravel-logs")
+		}
+	}
+
+	return names
+}
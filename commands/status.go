@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/larashed/agent-go/admin"
+)
+
+// StatusCommand queries a running agent through its admin socket
+type StatusCommand struct {
+	client *admin.Client
+}
+
+// NewStatusCommand creates an instance of `StatusCommand`
+func NewStatusCommand(adminSocket string) *StatusCommand {
+	return &StatusCommand{
+		client: admin.NewClient(adminSocket),
+	}
+}
+
+// Run applies the requested actions and prints the agent's status
+func (c *StatusCommand) Run(isJSON, flush bool, logLevel string) error {
+	status, err := c.client.Status()
+	if err == nil && len(logLevel) > 0 {
+		status, err = c.client.SetLogLevel(logLevel)
+	}
+	if err == nil && flush {
+		status, err = c.client.Flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	if isJSON {
+		j, _ := json.Marshal(status)
+		fmt.Println(string(j))
+
+		return nil
+	}
+
+	printStatus(os.Stdout, status)
+
+	return nil
+}
+
+func printStatus(w io.Writer, status *admin.Status) {
+	fmt.Fprintf(w, "Version:     %s (%s)\n", status.Version, status.Commit)
+	fmt.Fprintf(w, "PID:         %d\n", status.PID)
+	fmt.Fprintf(w, "Uptime:      %s, since %s\n", status.Uptime, status.StartedAt)
+	fmt.Fprintf(w, "Log level:   %s\n", status.LogLevel)
+	fmt.Fprintf(w, "Collectors:  %s\n", strings.Join(status.Collectors, ", "))
+
+	var buckets []string
+	for name := range status.Buckets {
+		buckets = append(buckets, name)
+	}
+	sort.Strings(buckets)
+
+	fmt.Fprintln(w, "Queued:")
+	for _, name := range buckets {
+		fmt.Fprintf(w, "  %-18s %d\n", name, status.Buckets[name])
+	}
+
+	var streams []string
+	for name := range status.Streams {
+		streams = append(streams, name)
+	}
+	sort.Strings(streams)
+
+	fmt.Fprintln(w, "Sent:")
+	for _, name := range streams {
+		stream := status.Streams[name]
+		lastSuccess := stream.LastSuccessAt
+		if len(lastSuccess) == 0 {
+			lastSuccess = "never"
+		}
+		fmt.Fprintf(w, "  %-18s last success %s, %d ok, %d failed\n", name, lastSuccess, stream.Successes, stream.Failures)
+	}
+
+	if len(status.LastError) > 0 {
+		fmt.Fprintf(w, "Last error:  %s at %s\n", status.LastError, status.LastErrorAt)
+	}
+
+	if status.Sender != nil {
+		fmt.Fprintf(w, "Sender:      %d app metrics received, %d sent, %d discarded; API calls %d ok, %d failed, %d empty\n",
+			status.Sender.AppMetricsReceived,
+			status.Sender.AppMetricsSent,
+			status.Sender.DiscardedItems,
+			status.Sender.APICallsSuccess,
+			status.Sender.APICallsFail,
+			status.Sender.APICallsEmpty,
+		)
+	}
+
+	config, _ := json.MarshalIndent(status.Config, "  ", "  ")
+	fmt.Fprintf(w, "Config:\n  %s\n", config)
+}
@@ -2,8 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 )
 
+const maskedValue = "********"
+
 // Config holds agent configuration
 type Config struct {
 	ApiUrl         string //nolint:golint
@@ -18,8 +24,119 @@ type Config struct {
 	PathProcfs     string
 	PathSysfs      string
 
+	AdminSocket string
+
+	// SocketPermissions is applied to unix sockets when non-zero, e.g. to let pods running as another user connect
+	SocketPermissions os.FileMode
+
 	CollectServerResources bool
 	CollectAppMetrics      bool
+
+	CollectProcesses     bool
+	ProcessLimit         int
+	RedactProcessCmdline bool
+
+	CollectPHPFPM    bool
+	PHPFPMEndpoints  []string
+	PHPFPMStatusPath string
+
+	NginxStatusURLs  []string
+	ApacheStatusURLs []string
+
+	MySQLDSN string
+
+	RedisAddress   string
+	RedisPassword  string
+	RedisDatabase  int
+	RedisKeyPrefix string
+	RedisQueues    []string
+
+	CollectQueueWorkers bool
+	HorizonPrefix       string
+
+	MonitorScheduler      bool
+	SchedulerMissedWindow time.Duration
+
+	LaravelPaths   []string
+	LogStateFile   string
+	LogSampleLimit int
+
+	TLSEndpoints        []string
+	TLSCertificateFiles []string
+
+	CollectPackageUpdates bool
+
+	CollectServices bool
+	SystemdUnits    []string
+
+	VolumeSizeInterval time.Duration
+	VolumeSizeTimeout  time.Duration
+
+	CollectContainers bool
+	PodmanSocket      string
+	ContainerdSocket  string
+
+	CgroupContainerStats bool
+
+	Kubernetes         bool
+	KubeletURL         string
+	KubeletInsecureTLS bool
+}
+
+// Masked returns a copy of the configuration with secrets replaced
+func (c *Config) Masked() *Config {
+	masked := *c
+
+	if len(masked.AppKey) > 0 {
+		masked.AppKey = maskedValue
+	}
+	if len(masked.RedisPassword) > 0 {
+		masked.RedisPassword = maskedValue
+	}
+	masked.MySQLDSN = maskDSN(masked.MySQLDSN)
+	masked.NginxStatusURLs = maskURLs(masked.NginxStatusURLs)
+	masked.ApacheStatusURLs = maskURLs(masked.ApacheStatusURLs)
+
+	return &masked
+}
+
+// maskDSN masks the password of a `user:password@protocol(address)/dbname` DSN. Like the MySQL driver,
+// the database name follows the last slash and the credentials end at the last @ before it.
+func maskDSN(dsn string) string {
+	slash := strings.LastIndex(dsn, "/")
+	if slash < 0 {
+		return dsn
+	}
+	at := strings.LastIndex(dsn[:slash], "@")
+	if at < 0 {
+		return dsn
+	}
+	colon := strings.Index(dsn[:at], ":")
+	if colon < 0 {
+		return dsn
+	}
+
+	return dsn[:colon+1] + maskedValue + dsn[at:]
+}
+
+// maskURLs masks passwords in URLs' user info, `url.Redacted()` style
+func maskURLs(urls []string) []string {
+	if len(urls) == 0 {
+		return urls
+	}
+
+	masked := make([]string, len(urls))
+	for i, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			// it may still hold credentials
+			masked[i] = maskedValue
+			continue
+		}
+		masked[i] = u.Redacted()
+	}
+
+	return masked
 }
 
 func (c *Config) String() string {
@@ -1,13 +1,13 @@
-// copied from https://github.com/docker/cli/blob/master/cli/command/container/stats.go
-// created method to expose the `docker stats --no-stream` command output without actually outputting
+// based on https://github.com/docker/cli/blob/master/cli/command/container/stats.go
+// keeps streaming `docker stats` for running containers without outputting them
 
 package container
 
 import (
 	"context"
 	"sync"
+	"time"
 
-	"github.com/docker/cli/cli/command"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
@@ -15,123 +15,227 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-type statsOptions struct {
-	all      bool
-	noStream bool
-	noTrunc  bool
-	format   string
+// delay before subscribing to events again after the daemon connection is lost
+const eventsReconnectInterval = 5 * time.Second
+
+// delay before reopening a stats stream that ended while its container was still tracked
+var statsReconnectInterval = 2 * time.Second
+
+// StatsManager streams stats of running containers, following container start and die events
+type StatsManager struct {
+	client client.APIClient
+
+	cStats  stats
+	cancels map[string]context.CancelFunc
+	mu      sync.Mutex
+
+	watching bool
+	started  bool
+	ctx      context.Context
+	stop     context.CancelFunc
 }
 
-// GetStats collects docker container stats
-func GetStats(dockerCli *client.Client) ([]StatsEntry, error) {
-	var opts = statsOptions{
-		all:      false,
-		noStream: true,
-		noTrunc:  false,
-		format:   "",
-	}
-	closeChan := make(chan error)
-	ctx, doneFunc := context.WithCancel(context.Background())
-
-	// monitorContainerEvents watches for container creation and removal (only
-	// used when calling `docker stats` without arguments).
-	monitorContainerEvents := func(started chan<- struct{}, c chan events.Message) {
-		f := filters.NewArgs()
-		f.Add("type", "container")
-		options := types.EventsOptions{
-			Filters: f,
-		}
+// NewStatsManager creates a new instance of `StatsManager`
+func NewStatsManager(dockerCli client.APIClient) *StatsManager {
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &StatsManager{
+		client:  dockerCli,
+		cancels: make(map[string]context.CancelFunc),
+		ctx:     ctx,
+		stop:    stop,
+	}
+}
+
+// Start streaming stats. It waits for the first sample of already running containers,
+// calling it again after the manager has started is a no-op.
+func (m *StatsManager) Start() error {
+	m.mu.Lock()
+	if m.started {
+		m.mu.Unlock()
+		return nil
+	}
+	watching := m.watching
+	m.watching = true
+	m.mu.Unlock()
+
+	// subscribe before listing so containers started in between aren't missed
+	if !watching {
+		subscribed := make(chan struct{})
+		go m.watch(subscribed)
+		<-subscribed
+	}
+
+	waitFirst := &sync.WaitGroup{}
+	if err := m.sync(waitFirst); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.started = true
+	m.mu.Unlock()
+
+	// make sure each container gets at least one valid stat data
+	waitFirst.Wait()
 
-		eventq, errq := dockerCli.Events(ctx, options)
+	return nil
+}
 
-		// Whether we successfully subscribed to eventq or not, we can now
-		// unblock the main goroutine.
-		close(started)
+// Stop streaming stats
+func (m *StatsManager) Stop() {
+	m.stop()
+}
 
+// Snapshot returns the latest stats of every running container
+func (m *StatsManager) Snapshot() []StatsEntry {
+	ccstats := []StatsEntry{}
+	m.cStats.mu.Lock()
+	for _, c := range m.cStats.cs {
+		ccstats = append(ccstats, c.GetStatistics())
+	}
+	m.cStats.mu.Unlock()
+
+	return ccstats
+}
+
+// watch follows container events, resubscribing when the event stream fails
+func (m *StatsManager) watch(subscribed chan<- struct{}) {
+	f := filters.NewArgs()
+	f.Add("type", "container")
+	options := types.EventsOptions{
+		Filters: f,
+	}
+
+	for {
+		eventq, errq := m.client.Events(m.ctx, options)
+		if subscribed != nil {
+			close(subscribed)
+			subscribed = nil
+		}
+
+	events:
 		for {
 			select {
+			case <-m.ctx.Done():
+				return
 			case event := <-eventq:
-				c <- event
+				m.handle(event)
 			case err := <-errq:
-				closeChan <- err
-				return
+				log.Trace().Err(err).Msg("Docker event stream closed")
+				break events
 			}
 		}
-	}
 
-	// Get the daemonOSType if not set already
-	if daemonOSType == "" {
-		svctx := context.Background()
-		sv, err := dockerCli.ServerVersion(svctx)
-		if err != nil {
-			doneFunc()
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(eventsReconnectInterval):
+		}
 
-			return nil, err
+		// containers may have started or died while the stream was down
+		if err := m.sync(nil); err != nil {
+			log.Trace().Err(err).Msg("Failed to list containers")
 		}
-		daemonOSType = sv.Os
+	}
+}
+
+func (m *StatsManager) handle(event events.Message) {
+	if len(event.ID) < 12 {
+		return
 	}
 
-	// waitFirst is a WaitGroup to wait first stat data's reach for each container
-	waitFirst := &sync.WaitGroup{}
+	switch event.Action {
+	case "start":
+		m.add(event.ID[:12], nil)
+	case "die":
+		m.remove(event.ID[:12])
+	}
+}
 
-	cStats := stats{}
-	// getContainerList simulates creation event for all previously existing
-	// containers (only used when calling `docker stats` without arguments).
-	getContainerList := func() {
-		options := types.ContainerListOptions{
-			All: opts.all,
-		}
-		cs, err := dockerCli.ContainerList(ctx, options)
-		if err != nil {
-			log.Trace().Err(err).Msg("getContainerList")
-			closeChan <- err
-		}
-		for _, container := range cs {
-			s := NewStats(container.ID[:12])
-			if cStats.add(s) {
-				waitFirst.Add(1)
-				go collect(ctx, s, dockerCli, false, waitFirst)
-			}
-		}
+// sync starts streaming new containers and drops stopped ones
+func (m *StatsManager) sync(waitFirst *sync.WaitGroup) error {
+	cs, err := m.client.ContainerList(m.ctx, types.ContainerListOptions{})
+	if err != nil {
+		return err
 	}
 
-	started := make(chan struct{})
-	eh := command.InitEventHandler()
+	running := make(map[string]bool)
+	for _, container := range cs {
+		running[container.ID[:12]] = true
+		m.add(container.ID[:12], waitFirst)
+	}
 
-	eh.Handle("start", func(e events.Message) {
-		s := NewStats(e.ID[:12])
-		if cStats.add(s) {
-			waitFirst.Add(1)
-			go collect(ctx, s, dockerCli, false, waitFirst)
+	m.mu.Lock()
+	var stopped []string
+	for id := range m.cancels {
+		if !running[id] {
+			stopped = append(stopped, id)
 		}
-	})
-
-	eh.Handle("die", func(e events.Message) {
-		cStats.remove(e.ID[:12])
-	})
-
-	eventChan := make(chan events.Message)
-	go eh.Watch(eventChan)
-	go monitorContainerEvents(started, eventChan)
-	defer func() {
-		doneFunc()
-		close(eventChan)
-	}()
-	<-started
-
-	// Start a short-lived goroutine to retrieve the initial list of
-	// containers.
-	getContainerList()
-
-	// make sure each container get at least one valid stat data
-	waitFirst.Wait()
+	}
+	m.mu.Unlock()
 
-	ccstats := []StatsEntry{}
-	cStats.mu.Lock()
-	for _, c := range cStats.cs {
-		ccstats = append(ccstats, c.GetStatistics())
+	for _, id := range stopped {
+		m.remove(id)
+	}
+
+	return nil
+}
+
+func (m *StatsManager) add(id string, waitFirst *sync.WaitGroup) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := NewStats(id)
+	if !m.cStats.add(s) {
+		return
+	}
+
+	if waitFirst == nil {
+		waitFirst = &sync.WaitGroup{}
+	}
+	waitFirst.Add(1)
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancels[id] = cancel
+	go m.collect(ctx, id, s, waitFirst)
+}
+
+// collect streams stats of a container. When the stream fails or ends without the container
+// being removed, e.g. while dockerd restarts, the container is dropped and listed again later.
+func (m *StatsManager) collect(ctx context.Context, id string, s *Stats, waitFirst *sync.WaitGroup) {
+	collect(ctx, s, m.client, true, waitFirst)
+
+	m.mu.Lock()
+	// removed containers are cancelled under the lock, an open context means the entry is still ours
+	if ctx.Err() != nil {
+		m.mu.Unlock()
+		return
 	}
-	cStats.mu.Unlock()
+	m.cancels[id]()
+	delete(m.cancels, id)
+	m.cStats.remove(id)
+	m.mu.Unlock()
 
-	return ccstats, nil
+	log.Trace().Str("container", id).Msg("Docker stats stream closed")
+
+	select {
+	case <-m.ctx.Done():
+		return
+	case <-time.After(statsReconnectInterval):
+	}
+
+	if err := m.sync(nil); err != nil {
+		log.Trace().Err(err).Msg("Failed to list containers")
+	}
+}
+
+func (m *StatsManager) remove(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+		delete(m.cancels, id)
+	}
+	m.cStats.remove(id)
 }
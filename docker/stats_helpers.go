@@ -20,11 +20,6 @@ type stats struct {
 	cs []*Stats
 }
 
-// daemonOSType is set once we have at least one stat for a container
-// from the daemon. It is used to ensure we print the right header based
-// on the daemon platform.
-var daemonOSType string
-
 func (s *stats) add(cs *Stats) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -45,7 +40,8 @@ func (s *stats) remove(id string) {
 
 func (s *stats) isKnownContainer(cid string) (int, bool) {
 	for i, c := range s.cs {
-		if c.Container == cid {
+		// statistics are written concurrently by the streaming goroutine
+		if c.GetStatistics().Container == cid {
 			return i, true
 		}
 	}
@@ -88,8 +84,16 @@ func collect(ctx context.Context, s *Stats, cli client.APIClient, streamStats bo
 			)
 
 			if err := dec.Decode(&v); err != nil {
+				// the stream is closed once the container is removed from the manager
+				if ctx.Err() != nil {
+					return
+				}
 				dec = json.NewDecoder(io.MultiReader(dec.Buffered(), response.Body))
-				u <- err
+				select {
+				case u <- err:
+				case <-ctx.Done():
+					return
+				}
 				if err == io.EOF {
 					break
 				}
@@ -97,9 +101,7 @@ func collect(ctx context.Context, s *Stats, cli client.APIClient, streamStats bo
 				continue
 			}
 
-			daemonOSType = response.OSType
-
-			if daemonOSType != "windows" {
+			if response.OSType != "windows" {
 				previousCPU = v.PreCPUStats.CPUUsage.TotalUsage
 				previousSystem = v.PreCPUStats.SystemUsage
 				cpuPercent = calculateCPUPercentUnix(previousCPU, previousSystem, v)
@@ -128,7 +130,11 @@ func collect(ctx context.Context, s *Stats, cli client.APIClient, streamStats bo
 				BlockWrite:       float64(blkWrite),
 				PidsCurrent:      pidsStatsCurrent,
 			})
-			u <- nil
+			select {
+			case u <- nil:
+			case <-ctx.Done():
+				return
+			}
 			if !streamStats {
 				return
 			}
@@ -136,6 +142,8 @@ func collect(ctx context.Context, s *Stats, cli client.APIClient, streamStats bo
 	}()
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case <-time.After(2 * time.Second):
 			// zero out the values if we have not received an update within
 			// the specified duration.
@@ -148,7 +156,8 @@ func collect(ctx context.Context, s *Stats, cli client.APIClient, streamStats bo
 		case err := <-u:
 			s.SetError(err)
 			if err == io.EOF {
-				break
+				// the container stopped, its stream won't resume
+				return
 			}
 			if err != nil {
 				continue
@@ -0,0 +1,136 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+type dockerStandIn struct {
+	client.APIClient
+
+	mu         sync.Mutex
+	containers []types.Container
+	streams    map[string]int
+	events     chan events.Message
+	errs       chan error
+
+	// the first stream of these containers closes after a single sample
+	closing map[string]bool
+}
+
+func (d *dockerStandIn) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.containers, nil
+}
+
+func (d *dockerStandIn) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	return d.events, d.errs
+}
+
+func (d *dockerStandIn) ContainerStats(ctx context.Context, container string, stream bool) (types.ContainerStats, error) {
+	d.mu.Lock()
+	d.streams[container]++
+	closing := d.closing[container] && d.streams[container] == 1
+	d.mu.Unlock()
+
+	r, w := io.Pipe()
+	go func() {
+		defer w.Close()
+
+		enc := json.NewEncoder(w)
+		for usage := uint64(1); ; usage++ {
+			v := types.StatsJSON{Name: "/" + container, ID: container}
+			v.MemoryStats.Usage = usage * 1024
+			v.MemoryStats.Limit = 1024 * 1024
+			if err := enc.Encode(v); err != nil || closing {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}()
+
+	return types.ContainerStats{Body: r, OSType: "linux"}, nil
+}
+
+func (d *dockerStandIn) streamCount(container string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.streams[container]
+}
+
+func TestStatsManager(t *testing.T) {
+	standIn := &dockerStandIn{
+		containers: []types.Container{{ID: "aaaaaaaaaaaa0000"}},
+		streams:    make(map[string]int),
+		events:     make(chan events.Message),
+		errs:       make(chan error),
+	}
+
+	manager := NewStatsManager(standIn)
+	defer manager.Stop()
+
+	assert.NoError(t, manager.Start())
+
+	stats := manager.Snapshot()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "aaaaaaaaaaaa", stats[0].Container)
+	assert.True(t, stats[0].Memory > 0)
+
+	// snapshots reuse the open stream
+	assert.NoError(t, manager.Start())
+	manager.Snapshot()
+	assert.Equal(t, 1, standIn.streamCount("aaaaaaaaaaaa"))
+
+	standIn.events <- events.Message{ID: "bbbbbbbbbbbb0000", Action: "start"}
+	assert.Eventually(t, func() bool {
+		return len(manager.Snapshot()) == 2 && manager.Snapshot()[1].Memory > 0
+	}, time.Second, 10*time.Millisecond)
+
+	standIn.events <- events.Message{ID: "aaaaaaaaaaaa0000", Action: "die"}
+	assert.Eventually(t, func() bool {
+		stats := manager.Snapshot()
+		return len(stats) == 1 && stats[0].Container == "bbbbbbbbbbbb"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStatsManagerReconnects(t *testing.T) {
+	statsReconnectInterval = 10 * time.Millisecond
+
+	standIn := &dockerStandIn{
+		containers: []types.Container{{ID: "aaaaaaaaaaaa0000"}},
+		streams:    make(map[string]int),
+		closing:    map[string]bool{"aaaaaaaaaaaa": true},
+		events:     make(chan events.Message),
+		errs:       make(chan error),
+	}
+
+	manager := NewStatsManager(standIn)
+	defer manager.Stop()
+
+	assert.NoError(t, manager.Start())
+
+	assert.Eventually(t, func() bool {
+		return standIn.streamCount("aaaaaaaaaaaa") == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		stats := manager.Snapshot()
+		return len(stats) == 1 && stats[0].Memory > 1024
+	}, time.Second, 10*time.Millisecond)
+}
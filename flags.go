@@ -1,6 +1,8 @@
 package main
 
 import (
+	"time"
+
 	"github.com/urfave/cli/v2"
 )
 
@@ -20,6 +22,47 @@ const (
 
 	CollectServerResourcesFlagName    = "collect-server-resources"
 	CollectApplicationMetricsFlagName = "collect-application-metrics"
+	CollectProcessesFlagName          = "collect-processes"
+	ProcessLimitFlagName              = "process-limit"
+	RedactProcessCmdlineFlagName      = "process-redact-cmdline"
+	CollectPHPFPMFlagName             = "collect-php-fpm"
+	PHPFPMEndpointFlagName            = "php-fpm-address"
+	PHPFPMStatusPathFlagName          = "php-fpm-status-path"
+	NginxStatusURLFlagName            = "nginx-status-url"
+	ApacheStatusURLFlagName           = "apache-status-url"
+	MySQLDSNFlagName                  = "mysql-dsn"
+	RedisAddressFlagName              = "redis-address"
+	RedisPasswordFlagName             = "redis-password"
+	RedisDatabaseFlagName             = "redis-database"
+	RedisKeyPrefixFlagName            = "redis-key-prefix"
+	RedisQueueFlagName                = "redis-queue"
+	CollectQueueWorkersFlagName       = "collect-queue-workers"
+	HorizonPrefixFlagName             = "horizon-prefix"
+	MonitorSchedulerFlagName          = "monitor-scheduler"
+	SchedulerMissedWindowFlagName     = "scheduler-missed-window"
+	LaravelPathFlagName               = "laravel-path"
+	LogStateFileFlagName              = "log-state-file"
+	LogSampleLimitFlagName            = "log-sample-limit"
+	TLSEndpointFlagName               = "tls-endpoint"
+	TLSCertificateFlagName            = "tls-certificate"
+	CollectPackageUpdatesFlagName     = "collect-package-updates"
+	SystemdUnitFlagName               = "systemd-unit"
+	VolumeSizeIntervalFlagName        = "volume-size-interval"
+	VolumeSizeTimeoutFlagName         = "volume-size-timeout"
+	PodmanSocketFlagName              = "podman-socket"
+	ContainerdSocketFlagName          = "containerd-socket"
+	CgroupContainerStatsFlagName      = "cgroup-container-stats"
+	CollectServicesFlagName           = "collect-services"
+	AdminSocketFlagName               = "admin-socket"
+	FlushFlagName                     = "flush"
+	SetLogLevelFlagName               = "set-log-level"
+	CollectContainersFlagName         = "collect-containers"
+	CompactFlagName                   = "compact"
+	SampleIntervalFlagName            = "sample-interval"
+	SocketPermissionsFlagName         = "socket-permissions"
+	KubernetesFlagName                = "kubernetes"
+	KubeletURLFlagName                = "kubelet-url"
+	KubeletInsecureTLSFlagName        = "kubelet-insecure-tls"
 )
 
 var (
@@ -54,6 +97,24 @@ var (
 		Name:  SocketAddressOldFlagName,
 		Usage: "Socket address (deprecated, use --socket-address instead)",
 	}
+	AdminSocketFlag = &cli.StringFlag{
+		Name:  AdminSocketFlagName,
+		Usage: "Unix socket serving the agent's status and control API to the status command, empty disables it",
+		Value: "/run/larashed-agent/admin.sock",
+	}
+	FlushFlag = &cli.BoolFlag{
+		Name:  FlushFlagName,
+		Usage: "Send queued metrics right away and queue a server metric collection, sent in the background",
+		Value: false,
+	}
+	SetLogLevelFlag = &cli.StringFlag{
+		Name:  SetLogLevelFlagName,
+		Usage: "Change the running agent's logging level (info, debug, trace) until it restarts",
+	}
+	SocketPermissionsFlag = &cli.StringFlag{
+		Name:  SocketPermissionsFlagName,
+		Usage: "Octal file mode of the unix socket, e.g. 0666 to accept connections from other users",
+	}
 	LoggingLevelFlag = &cli.StringFlag{
 		Name:  LoggingLevelFlagName,
 		Usage: "Logging level (info, debug, trace)",
@@ -87,4 +148,175 @@ var (
 		Usage: "Collect application metrics",
 		Value: true,
 	}
+	CollectProcessesFlag = &cli.BoolFlag{
+		Name:  CollectProcessesFlagName,
+		Usage: "Collect top processes by CPU and memory usage",
+		Value: false,
+	}
+	ProcessLimitFlag = &cli.IntFlag{
+		Name:  ProcessLimitFlagName,
+		Usage: "Number of top processes to report",
+		Value: 10,
+	}
+	RedactProcessCmdlineFlag = &cli.BoolFlag{
+		Name:  RedactProcessCmdlineFlagName,
+		Usage: "Mask credentials in reported process command lines",
+		Value: true,
+	}
+	CollectPHPFPMFlag = &cli.BoolFlag{
+		Name:  CollectPHPFPMFlagName,
+		Usage: "Collect PHP-FPM pool status",
+		Value: false,
+	}
+	PHPFPMEndpointFlag = &cli.StringSliceFlag{
+		Name:  PHPFPMEndpointFlagName,
+		Usage: "PHP-FPM pool address (unix:/path/to/socket, host:port). Pools are discovered from PHP-FPM config when omitted",
+	}
+	PHPFPMStatusPathFlag = &cli.StringFlag{
+		Name:  PHPFPMStatusPathFlagName,
+		Usage: "PHP-FPM status path (pm.status_path) used with --" + PHPFPMEndpointFlagName,
+		Value: "/status",
+	}
+	NginxStatusURLFlag = &cli.StringSliceFlag{
+		Name:  NginxStatusURLFlagName,
+		Usage: "nginx stub_status URL, e.g. http://127.0.0.1/nginx_status",
+	}
+	ApacheStatusURLFlag = &cli.StringSliceFlag{
+		Name:  ApacheStatusURLFlagName,
+		Usage: "Apache mod_status URL, e.g. http://127.0.0.1/server-status?auto",
+	}
+	MySQLDSNFlag = &cli.StringFlag{
+		Name:  MySQLDSNFlagName,
+		Usage: "MySQL/MariaDB DSN used to collect server health, e.g. user:password@tcp(127.0.0.1:3306)/",
+	}
+	RedisAddressFlag = &cli.StringFlag{
+		Name:  RedisAddressFlagName,
+		Usage: "Redis address (host:port or socket path) used to collect Redis stats and Laravel queue lengths",
+	}
+	RedisPasswordFlag = &cli.StringFlag{
+		Name:  RedisPasswordFlagName,
+		Usage: "Redis password",
+	}
+	RedisDatabaseFlag = &cli.IntFlag{
+		Name:  RedisDatabaseFlagName,
+		Usage: "Redis database holding Laravel queues",
+		Value: 0,
+	}
+	RedisKeyPrefixFlag = &cli.StringFlag{
+		Name:  RedisKeyPrefixFlagName,
+		Usage: "Laravel Redis key prefix, e.g. laravel_database_",
+	}
+	RedisQueueFlag = &cli.StringSliceFlag{
+		Name:  RedisQueueFlagName,
+		Usage: "Laravel queue name to report the length of",
+		Value: cli.NewStringSlice("default"),
+	}
+	CollectQueueWorkersFlag = &cli.BoolFlag{
+		Name:  CollectQueueWorkersFlagName,
+		Usage: "Collect Laravel queue worker and Horizon processes",
+		Value: false,
+	}
+	HorizonPrefixFlag = &cli.StringFlag{
+		Name:  HorizonPrefixFlagName,
+		Usage: "Laravel Horizon key prefix (horizon.prefix), e.g. laravel_horizon:. Requires --" + RedisAddressFlagName,
+	}
+	MonitorSchedulerFlag = &cli.BoolFlag{
+		Name:  MonitorSchedulerFlagName,
+		Usage: "Detect missed Laravel scheduler (schedule:run) executions",
+		Value: false,
+	}
+	SchedulerMissedWindowFlag = &cli.DurationFlag{
+		Name:  SchedulerMissedWindowFlagName,
+		Usage: "Report a missed schedule when schedule:run isn't seen within this duration",
+		Value: 2 * time.Minute,
+	}
+	LaravelPathFlag = &cli.StringSliceFlag{
+		Name:  LaravelPathFlagName,
+		Usage: "Laravel application root whose storage/logs/laravel*.log files are tailed",
+	}
+	LogStateFileFlag = &cli.StringFlag{
+		Name:  LogStateFileFlagName,
+		Usage: "File storing log read offsets across restarts",
+		Value: "/var/lib/larashed/log-offsets.json",
+	}
+	LogSampleLimitFlag = &cli.IntFlag{
+		Name:  LogSampleLimitFlagName,
+		Usage: "Maximum ERROR and more severe log entries forwarded per application per interval",
+		Value: 10,
+	}
+	TLSEndpointFlag = &cli.StringSliceFlag{
+		Name:  TLSEndpointFlagName,
+		Usage: "host:port whose TLS certificate expiry and chain is checked",
+	}
+	TLSCertificateFlag = &cli.StringSliceFlag{
+		Name:  TLSCertificateFlagName,
+		Usage: "PEM certificate file or glob whose expiry and chain is checked, e.g. /etc/letsencrypt/live/*/fullchain.pem",
+	}
+	CollectPackageUpdatesFlag = &cli.BoolFlag{
+		Name:  CollectPackageUpdatesFlagName,
+		Usage: "Count pending apt/dnf package and security updates from local metadata",
+		Value: false,
+	}
+	CollectServicesFlag = &cli.BoolFlag{
+		Name:  CollectServicesFlagName,
+		Usage: "Collect systemd services",
+		Value: true,
+	}
+	SystemdUnitFlag = &cli.StringSliceFlag{
+		Name:  SystemdUnitFlagName,
+		Usage: "systemd service (glob) to report, e.g. php*-fpm. All services are reported when omitted",
+	}
+	VolumeSizeIntervalFlag = &cli.DurationFlag{
+		Name:  VolumeSizeIntervalFlagName,
+		Usage: "How often Docker volume sizes are measured in the background, 0 disables it",
+		Value: 10 * time.Minute,
+	}
+	VolumeSizeTimeoutFlag = &cli.DurationFlag{
+		Name:  VolumeSizeTimeoutFlagName,
+		Usage: "Maximum time spent measuring a single volume, larger volumes keep their last complete size",
+		Value: time.Minute,
+	}
+	CollectContainersFlag = &cli.BoolFlag{
+		Name:  CollectContainersFlagName,
+		Usage: "Collect Docker, Podman and containerd containers",
+		Value: true,
+	}
+	PodmanSocketFlag = &cli.StringFlag{
+		Name:  PodmanSocketFlagName,
+		Usage: "Podman API socket. Rootful and rootless sockets under /run are detected when omitted",
+	}
+	ContainerdSocketFlag = &cli.StringFlag{
+		Name:  ContainerdSocketFlagName,
+		Usage: "containerd socket, containers are listed through its API. /run/containerd/containerd.sock is used when omitted",
+	}
+	CgroupContainerStatsFlag = &cli.BoolFlag{
+		Name:  CgroupContainerStatsFlagName,
+		Usage: "Read Docker and Podman container usage from cgroups under --path-sys instead of the stats API",
+		Value: false,
+	}
+	KubernetesFlag = &cli.BoolFlag{
+		Name:  KubernetesFlagName,
+		Usage: "Run as a Kubernetes DaemonSet, containers are tagged with their pod through the kubelet",
+		Value: false,
+	}
+	KubeletURLFlag = &cli.StringFlag{
+		Name:  KubeletURLFlagName,
+		Usage: "Kubelet API URL used with --kubernetes",
+		Value: "https://127.0.0.1:10250",
+	}
+	KubeletInsecureTLSFlag = &cli.BoolFlag{
+		Name:  KubeletInsecureTLSFlagName,
+		Usage: "Skip verifying the kubelet's certificate, needed when kubelet serving certificates are self-signed",
+		Value: false,
+	}
+	CompactFlag = &cli.BoolFlag{
+		Name:  CompactFlagName,
+		Usage: "Output JSON on a single line",
+		Value: false,
+	}
+	SampleIntervalFlag = &cli.DurationFlag{
+		Name:  SampleIntervalFlagName,
+		Usage: "Collect twice, this far apart, so CPU usage and rates measured between collections are filled in",
+		Value: 0,
+	}
 )
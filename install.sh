@@ -330,6 +330,9 @@ install_systemd_unit() {
   echo "TimeoutSec=$SYSTEMD_TIMEOUT" >>"$SYSTEMD_UNIT_PATH" || return 1
   echo "User=$UNIX_USERNAME" >>"$SYSTEMD_UNIT_PATH" || return 1
   echo "Group=$UNIX_USERNAME" >>"$SYSTEMD_UNIT_PATH" || return 1
+  echo "StateDirectory=larashed" >>"$SYSTEMD_UNIT_PATH" || return 1
+  echo "RuntimeDirectory=larashed-agent" >>"$SYSTEMD_UNIT_PATH" || return 1
+  echo "RuntimeDirectoryMode=0700" >>"$SYSTEMD_UNIT_PATH" || return 1
   echo "" >>"$SYSTEMD_UNIT_PATH" || return 1
 
   # build install part
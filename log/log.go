@@ -56,6 +56,18 @@ func ParseLoggingLevel(level string) zerolog.Level {
 	return zerolog.DebugLevel
 }
 
+// SetLevel changes the logging level of a running agent
+func SetLevel(level string) error {
+	switch level {
+	case levelDebug, levelInfo, levelTrace:
+		zerolog.SetGlobalLevel(ParseLoggingLevel(level))
+
+		return nil
+	}
+
+	return fmt.Errorf(`Unknown logging level "%s", use info, debug or trace`, level)
+}
+
 // trimLeftInclusive trims left part of the string up to and including the prefix.
 func trimLeftInclusive(s string, prefix string) (string, bool) {
 	start := strings.Index(s, prefix)
@@ -0,0 +1,71 @@
+package buckets
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// ContainerEventBucket holds container events waiting to be sent
+type ContainerEventBucket struct {
+	events []metrics.ContainerEvent
+	limit  int
+	mutex  sync.RWMutex
+}
+
+// NewContainerEventBucket creates a new `ContainerEventBucket` instance keeping at most `limit` events
+func NewContainerEventBucket(limit int) *ContainerEventBucket {
+	return &ContainerEventBucket{
+		events: make([]metrics.ContainerEvent, 0),
+		limit:  limit,
+		mutex:  sync.RWMutex{},
+	}
+}
+
+// Add an event to the bucket, dropping the oldest events over the limit
+func (b *ContainerEventBucket) Add(events ...metrics.ContainerEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.events = append(b.events, events...)
+	if len(b.events) > b.limit {
+		b.events = append(make([]metrics.ContainerEvent, 0), b.events[len(b.events)-b.limit:]...)
+	}
+}
+
+// Count the number of events in the bucket
+func (b *ContainerEventBucket) Count() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return len(b.events)
+}
+
+// Extract all events from the bucket
+func (b *ContainerEventBucket) Extract() []metrics.ContainerEvent {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	events := b.events
+	b.events = make([]metrics.ContainerEvent, 0)
+
+	return events
+}
+
+// Prepend returns events which failed to send to the front of the bucket
+func (b *ContainerEventBucket) Prepend(events []metrics.ContainerEvent) {
+	b.mutex.Lock()
+	pending := b.events
+	b.events = make([]metrics.ContainerEvent, 0)
+	b.mutex.Unlock()
+
+	b.Add(append(events, pending...)...)
+}
+
+// EventsString returns events as a JSON array
+func EventsString(events []metrics.ContainerEvent) string {
+	str, _ := json.Marshal(events)
+
+	return string(str)
+}
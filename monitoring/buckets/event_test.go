@@ -0,0 +1,29 @@
+package buckets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+func TestContainerEventBucketLimit(t *testing.T) {
+	bucket := NewContainerEventBucket(3)
+	bucket.Add(metrics.ContainerEvent{ID: "1"}, metrics.ContainerEvent{ID: "2"})
+	bucket.Add(metrics.ContainerEvent{ID: "3"}, metrics.ContainerEvent{ID: "4"})
+
+	events := bucket.Extract()
+	assert.Equal(t, 0, bucket.Count())
+	assert.Equal(t, "2", events[0].ID)
+	assert.Equal(t, "4", events[2].ID)
+
+	// failed sends go back in front of newer events
+	bucket.Add(metrics.ContainerEvent{ID: "5"})
+	bucket.Prepend(events)
+
+	events = bucket.Extract()
+	assert.Len(t, events, 3)
+	assert.Equal(t, "3", events[0].ID)
+	assert.Equal(t, "5", events[2].ID)
+}
@@ -0,0 +1,197 @@
+package collectors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const (
+	certificateTypeEndpoint = "endpoint"
+	certificateTypeFile     = "file"
+	certificateDialTimeout  = 5 * time.Second
+)
+
+// CertificateCollector checks expiry and chain validity of TLS certificates
+type CertificateCollector struct {
+	endpoints []string
+	files     []string
+	// nil uses the system roots
+	roots *x509.CertPool
+}
+
+// NewCertificateCollector creates a new instance of `CertificateCollector`.
+// Endpoints are host:port pairs, files may contain glob patterns.
+func NewCertificateCollector(endpoints, files []string) *CertificateCollector {
+	return &CertificateCollector{
+		endpoints: endpoints,
+		files:     files,
+	}
+}
+
+// Collect checks every configured endpoint and certificate file
+func (c *CertificateCollector) Collect() []metrics.Certificate {
+	certificates := make([]metrics.Certificate, len(c.endpoints))
+
+	// endpoints are checked concurrently so a slow host doesn't delay the others
+	var wg sync.WaitGroup
+	for i, endpoint := range c.endpoints {
+		wg.Add(1)
+		go func(i int, endpoint string) {
+			defer wg.Done()
+			certificates[i] = c.checkEndpoint(endpoint)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	for _, pattern := range c.files {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Trace().Err(err).Msgf("Invalid certificate pattern %s", pattern)
+			continue
+		}
+
+		// a plain path that doesn't match is reported, an empty glob is not
+		if len(files) == 0 && !hasGlobMeta(pattern) {
+			files = []string{pattern}
+		}
+
+		for _, file := range files {
+			certificates = append(certificates, c.checkFile(file))
+		}
+	}
+
+	return certificates
+}
+
+func (c *CertificateCollector) checkEndpoint(endpoint string) metrics.Certificate {
+	certificate := metrics.Certificate{Source: endpoint, Type: certificateTypeEndpoint}
+
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		certificate.Error = err.Error()
+		return certificate
+	}
+
+	// verification is done separately so expired or invalid certificates are still reported
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: certificateDialTimeout}, "tcp", endpoint, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true, //nolint:gosec
+	})
+	if err != nil {
+		certificate.Error = err.Error()
+		return certificate
+	}
+	defer conn.Close()
+
+	chain := conn.ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		certificate.Error = "no certificates presented"
+		return certificate
+	}
+
+	serverName := host
+	if net.ParseIP(host) != nil {
+		serverName = ""
+	}
+
+	c.describe(&certificate, chain, serverName)
+
+	return certificate
+}
+
+func (c *CertificateCollector) checkFile(file string) metrics.Certificate {
+	certificate := metrics.Certificate{Source: file, Type: certificateTypeFile}
+
+	chain, err := readCertificates(file)
+	if err != nil {
+		certificate.Error = err.Error()
+		return certificate
+	}
+
+	c.describe(&certificate, chain, "")
+
+	return certificate
+}
+
+// describe fills certificate details from the leaf and verifies the chain
+func (c *CertificateCollector) describe(certificate *metrics.Certificate, chain []*x509.Certificate, serverName string) {
+	leaf := chain[0]
+
+	certificate.Subject = leaf.Subject.CommonName
+	certificate.Issuer = leaf.Issuer.CommonName
+	certificate.SANs = leaf.DNSNames
+	for _, ip := range leaf.IPAddresses {
+		certificate.SANs = append(certificate.SANs, ip.String())
+	}
+	certificate.NotBefore = leaf.NotBefore.Format(time.RFC3339)
+	certificate.NotAfter = leaf.NotAfter.Format(time.RFC3339)
+	// rounded down, a certificate which expired an hour ago is a day past expiry
+	certificate.DaysUntilExpiry = int(math.Floor(time.Until(leaf.NotAfter).Hours() / 24))
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         c.roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		certificate.Error = err.Error()
+		return
+	}
+
+	certificate.Valid = true
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// readCertificates reads PEM encoded certificates, the leaf first
+func readCertificates(file string) ([]*x509.Certificate, error) {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, contents = pem.Decode(contents)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to parse certificate")
+		}
+		chain = append(chain, cert)
+	}
+
+	if len(chain) == 0 {
+		return nil, errors.New("No PEM certificates found")
+	}
+
+	return chain, nil
+}
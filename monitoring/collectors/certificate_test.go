@@ -0,0 +1,90 @@
+package collectors
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCertificateCollector(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "certificates")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	expired := filepath.Join(dir, "expired", "fullchain.pem")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(expired), 0755))
+	assert.NoError(t, ioutil.WriteFile(expired, selfSignedPEM(t, time.Now().Add(-71*time.Hour)), 0644))
+	recent := filepath.Join(dir, "recent", "fullchain.pem")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(recent), 0755))
+	assert.NoError(t, ioutil.WriteFile(recent, selfSignedPEM(t, time.Now().Add(-3*time.Hour)), 0644))
+
+	collector := NewCertificateCollector(
+		[]string{strings.TrimPrefix(server.URL, "https://"), "127.0.0.1:1"},
+		[]string{filepath.Join(dir, "*", "fullchain.pem"), filepath.Join(dir, "none", "*.pem"), filepath.Join(dir, "missing.pem")},
+	)
+	collector.roots = x509.NewCertPool()
+	collector.roots.AddCert(server.Certificate())
+
+	certificates := collector.Collect()
+	assert.Len(t, certificates, 5)
+
+	assert.Equal(t, "endpoint", certificates[0].Type)
+	assert.True(t, certificates[0].Valid)
+	assert.Empty(t, certificates[0].Error)
+	assert.Contains(t, certificates[0].SANs, "example.com")
+	assert.Contains(t, certificates[0].SANs, "127.0.0.1")
+	assert.True(t, certificates[0].DaysUntilExpiry > 0)
+
+	assert.Equal(t, "127.0.0.1:1", certificates[1].Source)
+	assert.False(t, certificates[1].Valid)
+	assert.NotEmpty(t, certificates[1].Error)
+
+	assert.Equal(t, expired, certificates[2].Source)
+	assert.Equal(t, "file", certificates[2].Type)
+	assert.Equal(t, "expired.test", certificates[2].Subject)
+	assert.Equal(t, -3, certificates[2].DaysUntilExpiry)
+	assert.False(t, certificates[2].Valid)
+	assert.NotEmpty(t, certificates[2].Error)
+
+	// expired hours ago
+	assert.Equal(t, recent, certificates[3].Source)
+	assert.Equal(t, -1, certificates[3].DaysUntilExpiry)
+
+	assert.Equal(t, filepath.Join(dir, "missing.pem"), certificates[4].Source)
+	assert.NotEmpty(t, certificates[4].Error)
+}
+
+func selfSignedPEM(t *testing.T, notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "expired.test"},
+		DNSNames:     []string{"expired.test"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
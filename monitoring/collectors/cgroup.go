@@ -0,0 +1,384 @@
+package collectors
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/mem"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// dockerCgroupPattern matches the cgroup directory of a Docker container,
+// `docker-<id>.scope` with the systemd driver and `<id>` below `docker` with cgroupfs
+var dockerCgroupPattern = regexp.MustCompile(`^(?:docker-)?([0-9a-f]{64})(?:\.scope)?$`)
+
+// cgroupStats holds resource usage read from a container's cgroup
+type cgroupStats struct {
+	cpuUsageNS  uint64
+	memory      uint64 // Excludes inactive page cache, like `docker stats`
+	memoryLimit uint64 // Zero when unlimited
+	pids        uint64
+	blockRead   uint64
+	blockWrite  uint64
+	networkRx   uint64 // Zero when the container shares the host network
+	networkTx   uint64
+}
+
+type cpuSample struct {
+	usage uint64
+	at    time.Time
+}
+
+// cgroupSampler turns cumulative cgroup CPU usage into a percentage since the previous collection
+type cgroupSampler struct {
+	previous   map[string]cpuSample
+	current    map[string]cpuSample
+	hostMemory float64
+	now        time.Time
+}
+
+func newCgroupSampler() *cgroupSampler {
+	return &cgroupSampler{previous: make(map[string]cpuSample)}
+}
+
+// begin starts a collection, containers not filled before `end` are forgotten
+func (s *cgroupSampler) begin() {
+	s.now = time.Now()
+	s.current = make(map[string]cpuSample)
+
+	if vm, err := mem.VirtualMemory(); err == nil {
+		s.hostMemory = float64(vm.Total)
+	}
+}
+
+// fill sets the container's usage, like `docker stats` CPU is relative to a single core
+func (s *cgroupSampler) fill(cont *metrics.Container, stats *cgroupStats) {
+	cont.PIDs = stats.pids
+	cont.MemoryCurrent = float64(stats.memory)
+	cont.MemoryTotal = float64(stats.memoryLimit)
+	if cont.MemoryTotal == 0 {
+		cont.MemoryTotal = s.hostMemory
+	}
+	if cont.MemoryTotal > 0 {
+		cont.MemoryUsedPercentage = cont.MemoryCurrent / cont.MemoryTotal * 100
+	}
+
+	cont.BlockRead = float64(stats.blockRead)
+	cont.BlockWrite = float64(stats.blockWrite)
+	cont.NetworkInbound = float64(stats.networkRx)
+	cont.NetworkOutbound = float64(stats.networkTx)
+
+	sample := cpuSample{usage: stats.cpuUsageNS, at: s.now}
+	if previous, ok := s.previous[cont.ID]; ok && sample.usage >= previous.usage && s.now.After(previous.at) {
+		cont.CPUUsedPercentage = float64(sample.usage-previous.usage) / float64(s.now.Sub(previous.at)) * 100
+	}
+	s.current[cont.ID] = sample
+}
+
+func (s *cgroupSampler) end() {
+	s.previous = s.current
+}
+
+// hostCgroup returns a path inside the cgroup mount, honouring `HOST_SYS`
+func hostCgroup(parts ...string) string {
+	return hostPath("HOST_SYS", "/sys", append([]string{"fs", "cgroup"}, parts...)...)
+}
+
+// isCgroup2 reports whether the unified hierarchy is mounted at the cgroup root
+func isCgroup2() bool {
+	_, err := os.Stat(hostCgroup("cgroup.controllers"))
+
+	return err == nil
+}
+
+// processCgroups returns a process' cgroup path per v1 controller, the unified hierarchy is keyed by ""
+func processCgroups(pid int) (map[string]string, error) {
+	f, err := os.Open(hostProc(strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	paths := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if len(parts[1]) == 0 {
+			paths[""] = parts[2]
+			continue
+		}
+
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
+	}
+
+	return paths, scanner.Err()
+}
+
+// readCgroupStats reads usage of the cgroups a process belongs to
+func readCgroupStats(pid int) (*cgroupStats, error) {
+	paths, err := processCgroups(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats *cgroupStats
+	if isCgroup2() {
+		stats, err = readCgroup2Stats(resolveCgroupPath(hostCgroup(), paths[""]))
+	} else {
+		stats, err = readCgroup1Stats(func(controller string) string {
+			return resolveCgroupPath(hostCgroup(controller), paths[controller])
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stats.networkRx, stats.networkTx = readNetworkStats(pid)
+
+	return stats, nil
+}
+
+// resolveCgroupPath finds a cgroup below `root`. Inside a private cgroup namespace, such as a
+// Kubernetes pod, paths outside the agent's own cgroup start with "/..", the ancestors they skip are searched for.
+func resolveCgroupPath(root, path string) string {
+	if !strings.HasPrefix(path, "/..") {
+		return filepath.Join(root, path)
+	}
+
+	var levels int
+	for strings.HasPrefix(path, "/..") {
+		path = strings.TrimPrefix(path, "/..")
+		levels++
+	}
+
+	parts := []string{root}
+	for depth := 0; depth <= levels; depth++ {
+		if matches, _ := filepath.Glob(filepath.Join(append(parts, path)...)); len(matches) == 1 {
+			return matches[0]
+		}
+		parts = append(parts, "*")
+	}
+
+	return filepath.Join(root, path)
+}
+
+// dockerCgroups finds running Docker containers by their cgroups, keyed by container id.
+// Paths are relative to the unified hierarchy, or to each controller on v1.
+func dockerCgroups() map[string]string {
+	root := hostCgroup()
+	if !isCgroup2() {
+		root = hostCgroup("cpuacct")
+	}
+
+	var dirs []string
+	for _, pattern := range []string{"system.slice/docker-*.scope", "docker/*"} {
+		matches, _ := filepath.Glob(filepath.Join(root, pattern))
+		dirs = append(dirs, matches...)
+	}
+
+	cgroups := make(map[string]string)
+	for _, dir := range dirs {
+		match := dockerCgroupPattern.FindStringSubmatch(filepath.Base(dir))
+		if match == nil {
+			continue
+		}
+		cgroups[match[1]] = strings.TrimPrefix(dir, root)
+	}
+
+	return cgroups
+}
+
+// readCgroupPathStats reads usage of the cgroup at `path`, as returned by `dockerCgroups`
+func readCgroupPathStats(path string) (*cgroupStats, error) {
+	var stats *cgroupStats
+	var err error
+	procs := hostCgroup(path, "cgroup.procs")
+	if isCgroup2() {
+		stats, err = readCgroup2Stats(hostCgroup(path))
+	} else {
+		procs = hostCgroup("cpuacct", path, "cgroup.procs")
+		stats, err = readCgroup1Stats(func(controller string) string {
+			return hostCgroup(controller, path)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// any process in the cgroup shares the container's network namespace
+	contents, _ := ioutil.ReadFile(procs)
+	if fields := strings.Fields(string(contents)); len(fields) > 0 {
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			stats.networkRx, stats.networkTx = readNetworkStats(pid)
+		}
+	}
+
+	return stats, nil
+}
+
+func readCgroup2Stats(dir string) (*cgroupStats, error) {
+	cpu, err := readKeyValueFile(dir + "/cpu.stat")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &cgroupStats{cpuUsageNS: cpu["usage_usec"] * 1000}
+
+	memoryStat, _ := readKeyValueFile(dir + "/memory.stat")
+	stats.memory = withoutInactiveFile(readUintFile(dir+"/memory.current"), memoryStat["inactive_file"])
+	stats.memoryLimit = readUintFile(dir + "/memory.max")
+	stats.pids = readUintFile(dir + "/pids.current")
+	stats.blockRead, stats.blockWrite = readIOStat(dir + "/io.stat")
+
+	return stats, nil
+}
+
+func readCgroup1Stats(dir func(controller string) string) (*cgroupStats, error) {
+	usage, err := ioutil.ReadFile(dir("cpuacct") + "/cpuacct.usage")
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &cgroupStats{}
+	stats.cpuUsageNS, _ = strconv.ParseUint(strings.TrimSpace(string(usage)), 10, 64)
+
+	memoryStat, _ := readKeyValueFile(dir("memory") + "/memory.stat")
+	stats.memory = withoutInactiveFile(readUintFile(dir("memory")+"/memory.usage_in_bytes"), memoryStat["total_inactive_file"])
+	stats.memoryLimit = readUintFile(dir("memory") + "/memory.limit_in_bytes")
+	// v1 reports no limit as a page aligned int64 maximum
+	if stats.memoryLimit >= 1<<62 {
+		stats.memoryLimit = 0
+	}
+	stats.pids = readUintFile(dir("pids") + "/pids.current")
+
+	// the throttle policy is the only one accounting I/O without the CFQ scheduler
+	for _, file := range []string{"blkio.io_service_bytes_recursive", "blkio.throttle.io_service_bytes_recursive"} {
+		stats.blockRead, stats.blockWrite = readBlkioServiceBytes(dir("blkio") + "/" + file)
+		if stats.blockRead > 0 || stats.blockWrite > 0 {
+			break
+		}
+	}
+
+	return stats, nil
+}
+
+// readIOStat sums read and written bytes across devices in a v2 `io.stat` file
+func readIOStat(path string) (read, write uint64) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, 0
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		for _, field := range strings.Fields(line) {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			value, _ := strconv.ParseUint(parts[1], 10, 64)
+			switch parts[0] {
+			case "rbytes":
+				read += value
+			case "wbytes":
+				write += value
+			}
+		}
+	}
+
+	return read, write
+}
+
+// readBlkioServiceBytes sums read and written bytes across devices in a v1 `blkio` file
+func readBlkioServiceBytes(path string) (read, write uint64) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, 0
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		value, _ := strconv.ParseUint(fields[2], 10, 64)
+		switch fields[1] {
+		case "Read":
+			read += value
+		case "Write":
+			write += value
+		}
+	}
+
+	return read, write
+}
+
+// readNetworkStats sums received and transmitted bytes in a process' network namespace,
+// skipping loopback. Processes sharing the host network report nothing, like `docker stats`.
+func readNetworkStats(pid int) (rx, tx uint64) {
+	namespace, err := os.Readlink(hostProc(strconv.Itoa(pid), "ns", "net"))
+	if err != nil {
+		return 0, 0
+	}
+	if hostNamespace, err := os.Readlink(hostProc("1", "ns", "net")); err == nil && hostNamespace == namespace {
+		return 0, 0
+	}
+
+	contents, err := ioutil.ReadFile(hostProc(strconv.Itoa(pid), "net", "dev"))
+	if err != nil {
+		return 0, 0
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "lo" {
+			continue
+		}
+
+		// received bytes are the first of 8 receive columns, transmitted bytes follow them
+		fields := strings.Fields(parts[1])
+		if len(fields) < 9 {
+			continue
+		}
+		received, _ := strconv.ParseUint(fields[0], 10, 64)
+		transmitted, _ := strconv.ParseUint(fields[8], 10, 64)
+		rx += received
+		tx += transmitted
+	}
+
+	return rx, tx
+}
+
+func withoutInactiveFile(usage, inactive uint64) uint64 {
+	if inactive < usage {
+		return usage - inactive
+	}
+
+	return usage
+}
+
+// readUintFile reads a single number file, "max" and missing files read as zero
+func readUintFile(path string) uint64 {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	value, _ := strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+
+	return value
+}
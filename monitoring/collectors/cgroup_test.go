@@ -0,0 +1,141 @@
+package collectors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const netDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0
+  eth0:    2048      20    0    0    0     0          0         0     4096      40    0    0    0     0       0          0
+`
+
+func TestDockerCgroupStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(contents string, parts ...string) {
+		path := filepath.Join(append([]string{dir}, parts...)...)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	link := func(target string, parts ...string) {
+		path := filepath.Join(append([]string{dir}, parts...)...)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.Symlink(target, path))
+	}
+
+	id := strings.Repeat("ab", 32)
+	cgroup := []string{"sys", "fs", "cgroup", "system.slice", "docker-" + id + ".scope"}
+	write("", "sys", "fs", "cgroup", "cgroup.controllers")
+	write("", "sys", "fs", "cgroup", "system.slice", "cron.service", "cgroup.procs")
+	write("42\n43\n", append(cgroup, "cgroup.procs")...)
+	write("usage_usec 500\n", append(cgroup, "cpu.stat")...)
+	write("8388608\n", append(cgroup, "memory.current")...)
+	write("max\n", append(cgroup, "memory.max")...)
+	write("2\n", append(cgroup, "pids.current")...)
+	write("8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0\n8:16 rbytes=1 wbytes=1\n", append(cgroup, "io.stat")...)
+
+	write(netDev, "proc", "42", "net", "dev")
+	link("net:[4026531992]", "proc", "1", "ns", "net")
+	link("net:[4026532200]", "proc", "42", "ns", "net")
+
+	os.Setenv("HOST_PROC", filepath.Join(dir, "proc"))
+	defer os.Unsetenv("HOST_PROC")
+	os.Setenv("HOST_SYS", filepath.Join(dir, "sys"))
+	defer os.Unsetenv("HOST_SYS")
+
+	cgroups := dockerCgroups()
+	assert.Equal(t, map[string]string{id: "/system.slice/docker-" + id + ".scope"}, cgroups)
+
+	stats, err := readCgroupPathStats(cgroups[id])
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(500000), stats.cpuUsageNS)
+	assert.Equal(t, uint64(8388608), stats.memory)
+	assert.Equal(t, uint64(0), stats.memoryLimit)
+	assert.Equal(t, uint64(2), stats.pids)
+	assert.Equal(t, uint64(1025), stats.blockRead)
+	assert.Equal(t, uint64(2049), stats.blockWrite)
+	assert.Equal(t, uint64(2048), stats.networkRx)
+	assert.Equal(t, uint64(4096), stats.networkTx)
+
+	dc := &DockerClient{runtime: runtimeDocker, sampler: newCgroupSampler()}
+	dc.sampler.begin()
+	containers := dc.cgroupContainers(cgroups)
+	dc.sampler.end()
+	assert.Len(t, containers, 1)
+	assert.Equal(t, id[:12], containers[0].Name)
+	assert.Equal(t, float64(1025), containers[0].BlockRead)
+	assert.Equal(t, float64(4096), containers[0].NetworkOutbound)
+
+	// Docker is detected from its cgroups without a socket
+	os.Setenv("DOCKER_HOST", "unix://"+filepath.Join(dir, "docker.sock"))
+	defer os.Unsetenv("DOCKER_HOST")
+	runtimes := DetectRuntimes(RuntimeConfig{
+		PodmanSocket:     filepath.Join(dir, "podman.sock"),
+		ContainerdSocket: filepath.Join(dir, "containerd.sock"),
+	})
+	assert.Len(t, runtimes, 1)
+	containers, err = runtimes[0].FetchContainers()
+	runtimes[0].Close()
+	assert.NoError(t, err)
+	assert.Len(t, containers, 1)
+	assert.Equal(t, runtimeDocker, containers[0].Type)
+
+	// host networked containers report no traffic
+	assert.NoError(t, os.Remove(filepath.Join(dir, "proc", "42", "ns", "net")))
+	link("net:[4026531992]", "proc", "42", "ns", "net")
+	rx, tx := readNetworkStats(42)
+	assert.Equal(t, uint64(0), rx)
+	assert.Equal(t, uint64(0), tx)
+}
+
+func TestResolveCgroupPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pod := filepath.Join(dir, "kubepods.slice", "kubepods-besteffort.slice", "cri-containerd-abc.scope")
+	assert.NoError(t, os.MkdirAll(pod, 0755))
+
+	assert.Equal(t, filepath.Join(dir, "system.slice"), resolveCgroupPath(dir, "/system.slice"))
+	// seen from a container in kubepods-burstable.slice/<pod>/<container>
+	assert.Equal(t, pod, resolveCgroupPath(dir, "/../../../kubepods-besteffort.slice/cri-containerd-abc.scope"))
+}
+
+func TestReadCgroup1Stats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cgroup")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(controller, file, contents string) {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, controller), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, controller, file), []byte(contents), 0644))
+	}
+
+	write("cpuacct", "cpuacct.usage", "123456789\n")
+	write("memory", "memory.usage_in_bytes", "10485760\n")
+	write("memory", "memory.stat", "cache 0\ntotal_inactive_file 2097152\n")
+	write("memory", "memory.limit_in_bytes", "9223372036854771712\n")
+	write("pids", "pids.current", "3\n")
+	write("blkio", "blkio.io_service_bytes_recursive", "Total 0\n")
+	write("blkio", "blkio.throttle.io_service_bytes_recursive", "8:0 Read 4096\n8:0 Write 512\n8:0 Sync 0\n8:0 Total 4608\nTotal 4608\n")
+
+	stats, err := readCgroup1Stats(func(controller string) string {
+		return filepath.Join(dir, controller)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(123456789), stats.cpuUsageNS)
+	assert.Equal(t, uint64(8*1024*1024), stats.memory)
+	assert.Equal(t, uint64(0), stats.memoryLimit)
+	assert.Equal(t, uint64(3), stats.pids)
+	assert.Equal(t, uint64(4096), stats.blockRead)
+	assert.Equal(t, uint64(512), stats.blockWrite)
+}
@@ -0,0 +1,102 @@
+package collectors
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/pkg/errors"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	composeNumberLabel  = "com.docker.compose.container-number"
+	// containers started with `docker-compose run`
+	composeOneoffLabel = "com.docker.compose.oneoff"
+)
+
+// tagCompose sets the Docker Compose project and service a container belongs to
+func tagCompose(cont *metrics.Container, labels map[string]string) {
+	cont.ComposeProject = labels[composeProjectLabel]
+	cont.ComposeService = labels[composeServiceLabel]
+	cont.ComposeNumber, _ = strconv.Atoi(labels[composeNumberLabel])
+}
+
+// FetchComposeProjects summarises Docker Compose projects. Desired replicas come from all
+// containers created for a service, usage from the running `containers`.
+func (dc *DockerClient) FetchComposeProjects(containers []metrics.Container) ([]metrics.ComposeProject, error) {
+	f := filters.NewArgs()
+	f.Add("label", composeProjectLabel)
+
+	created, err := dc.client.ContainerList(context.Background(), types.ContainerListOptions{All: true, Filters: f})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch compose containers")
+	}
+
+	return composeProjects(created, containers), nil
+}
+
+func composeProjects(created []types.Container, running []metrics.Container) []metrics.ComposeProject {
+	projects := make(map[string]map[string]*metrics.ComposeService)
+	service := func(project, name string) *metrics.ComposeService {
+		if projects[project] == nil {
+			projects[project] = make(map[string]*metrics.ComposeService)
+		}
+		if projects[project][name] == nil {
+			projects[project][name] = &metrics.ComposeService{Name: name}
+		}
+
+		return projects[project][name]
+	}
+
+	for _, item := range created {
+		if item.Labels[composeOneoffLabel] == "True" {
+			continue
+		}
+		service(item.Labels[composeProjectLabel], item.Labels[composeServiceLabel]).DesiredReplicas++
+	}
+
+	for _, cont := range running {
+		if len(cont.ComposeProject) == 0 || cont.Labels[composeOneoffLabel] == "True" {
+			continue
+		}
+
+		s := service(cont.ComposeProject, cont.ComposeService)
+		s.RunningReplicas++
+		s.CPUUsedPercentage += cont.CPUUsedPercentage
+		s.MemoryCurrent += cont.MemoryCurrent
+	}
+
+	summaries := make([]metrics.ComposeProject, 0, len(projects))
+	for name, services := range projects {
+		project := metrics.ComposeProject{Name: name}
+		for _, s := range services {
+			// a container listed as running can't be missing from the created list
+			if s.DesiredReplicas < s.RunningReplicas {
+				s.DesiredReplicas = s.RunningReplicas
+			}
+
+			project.Services = append(project.Services, *s)
+			project.DesiredReplicas += s.DesiredReplicas
+			project.RunningReplicas += s.RunningReplicas
+			project.CPUUsedPercentage += s.CPUUsedPercentage
+			project.MemoryCurrent += s.MemoryCurrent
+		}
+
+		sort.Slice(project.Services, func(i, j int) bool {
+			return project.Services[i].Name < project.Services[j].Name
+		})
+		summaries = append(summaries, project)
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	return summaries
+}
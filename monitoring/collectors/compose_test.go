@@ -0,0 +1,51 @@
+package collectors
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+func TestComposeProjects(t *testing.T) {
+	labels := func(project, service, number string) map[string]string {
+		return map[string]string{composeProjectLabel: project, composeServiceLabel: service, composeNumberLabel: number}
+	}
+
+	created := []types.Container{
+		{Labels: labels("shop", "app", "1")},
+		{Labels: labels("shop", "app", "2")},
+		{Labels: labels("shop", "queue", "1")},
+		{Labels: labels("blog", "app", "1")},
+		{Labels: map[string]string{composeProjectLabel: "shop", composeServiceLabel: "app", composeOneoffLabel: "True"}},
+	}
+
+	var running []metrics.Container
+	for _, l := range []map[string]string{labels("shop", "app", "1"), labels("shop", "queue", "1"), labels("blog", "app", "1")} {
+		cont := metrics.Container{CPUUsedPercentage: 10, MemoryCurrent: 100, Labels: l}
+		tagCompose(&cont, l)
+		running = append(running, cont)
+	}
+	running = append(running, metrics.Container{Name: "standalone", CPUUsedPercentage: 50})
+
+	assert.Equal(t, "shop", running[0].ComposeProject)
+	assert.Equal(t, "app", running[0].ComposeService)
+	assert.Equal(t, 1, running[0].ComposeNumber)
+
+	projects := composeProjects(created, running)
+	assert.Len(t, projects, 2)
+
+	assert.Equal(t, "blog", projects[0].Name)
+	assert.Equal(t, "shop", projects[1].Name)
+	assert.Equal(t, 3, projects[1].DesiredReplicas)
+	assert.Equal(t, 2, projects[1].RunningReplicas)
+	assert.Equal(t, float64(20), projects[1].CPUUsedPercentage)
+	assert.Equal(t, float64(200), projects[1].MemoryCurrent)
+
+	assert.Equal(t, "app", projects[1].Services[0].Name)
+	assert.Equal(t, 2, projects[1].Services[0].DesiredReplicas)
+	assert.Equal(t, 1, projects[1].Services[0].RunningReplicas)
+	assert.Equal(t, "queue", projects[1].Services[1].Name)
+}
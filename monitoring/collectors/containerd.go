@@ -0,0 +1,201 @@
+package collectors
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/host"
+	"google.golang.org/grpc"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const containerdRequestTimeout = 10 * time.Second
+
+// Docker's own containers live in containerd's "moby" namespace and are reported by the Docker runtime
+const containerdDockerNamespace = "moby"
+
+// ociSpec is the part of a container's OCI runtime spec the agent reads
+type ociSpec struct {
+	Process struct {
+		Args []string `json:"args"`
+	} `json:"process"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// ContainerdRuntime reports containerd containers with a running task, listed through the containerd socket.
+// Usage is read from the tasks' cgroups.
+type ContainerdRuntime struct {
+	socket  string
+	conn    *grpc.ClientConn
+	sampler *cgroupSampler
+	mutex   sync.Mutex
+}
+
+// NewContainerdRuntime creates a new instance of `ContainerdRuntime`
+func NewContainerdRuntime(socket string) *ContainerdRuntime {
+	return &ContainerdRuntime{
+		socket:  socket,
+		sampler: newCgroupSampler(),
+	}
+}
+
+// Name returns the runtime name
+func (r *ContainerdRuntime) Name() string {
+	return runtimeContainerd
+}
+
+// Close the connection to containerd
+func (r *ContainerdRuntime) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+}
+
+// FetchContainers returns containers with a running task in every containerd namespace, with their usage
+func (r *ContainerdRuntime) FetchContainers() ([]metrics.Container, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.conn == nil {
+		// the connection is established in the background and re-established by gRPC when containerd restarts
+		conn, err := grpc.Dial(r.socket, grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", address)
+		}))
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to connect to containerd")
+		}
+		r.conn = conn
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerdRequestTimeout)
+	defer cancel()
+
+	list, err := namespacesapi.NewNamespacesClient(r.conn).List(ctx, &namespacesapi.ListNamespacesRequest{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list containerd namespaces")
+	}
+
+	r.sampler.begin()
+	defer r.sampler.end()
+
+	containers := make([]metrics.Container, 0)
+	for _, namespace := range list.Namespaces {
+		if namespace.Name == containerdDockerNamespace {
+			continue
+		}
+
+		namespaceContainers, err := r.fetchNamespace(namespaces.WithNamespace(ctx, namespace.Name))
+		if err != nil {
+			return nil, errors.Wrapf(err, `Failed to list containerd namespace "%s"`, namespace.Name)
+		}
+		containers = append(containers, namespaceContainers...)
+	}
+
+	return containers, nil
+}
+
+func (r *ContainerdRuntime) fetchNamespace(ctx context.Context) ([]metrics.Container, error) {
+	tasks, err := tasksapi.NewTasksClient(r.conn).List(ctx, &tasksapi.ListTasksRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	pids := make(map[string]uint32)
+	for _, process := range tasks.Tasks {
+		if process.Status == task.StatusRunning {
+			pids[process.ID] = process.Pid
+		}
+	}
+
+	list, err := containersapi.NewContainersClient(r.conn).List(ctx, &containersapi.ListContainersRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var containers []metrics.Container
+	for _, container := range list.Containers {
+		pid, ok := pids[container.ID]
+		if !ok {
+			continue
+		}
+
+		cont, sandbox := containerdContainer(container)
+		// pod sandboxes only hold the pod's namespaces, their pause process isn't a workload
+		if sandbox {
+			continue
+		}
+
+		stats, err := readCgroupStats(int(pid))
+		if err != nil {
+			// the task exited after it was listed
+			continue
+		}
+
+		cont.StartedAt = processStartedAt(int32(pid))
+		r.sampler.fill(&cont, stats)
+		containers = append(containers, cont)
+	}
+
+	return containers, nil
+}
+
+// containerdContainer converts containerd container metadata, reporting whether it's a CRI pod sandbox
+func containerdContainer(container containersapi.Container) (metrics.Container, bool) {
+	cont := metrics.Container{
+		ID:        container.ID,
+		Name:      container.ID,
+		Type:      runtimeContainerd,
+		State:     "running",
+		Image:     container.Image,
+		Labels:    container.Labels,
+		CreatedAt: container.CreatedAt.Unix(),
+	}
+
+	var spec ociSpec
+	if container.Spec != nil {
+		_ = json.Unmarshal(container.Spec.Value, &spec)
+	}
+	cont.Command = strings.Join(spec.Process.Args, " ")
+
+	if name := spec.Annotations["io.kubernetes.cri.container-name"]; len(name) > 0 {
+		cont.Name = name
+	} else if name := container.Labels["nerdctl/name"]; len(name) > 0 {
+		cont.Name = name
+	}
+
+	sandbox := container.Labels["io.cri-containerd.kind"] == "sandbox" ||
+		spec.Annotations["io.kubernetes.cri.container-type"] == "sandbox"
+
+	return cont, sandbox
+}
+
+// processStartedAt returns when a process started, empty when it can't be read
+func processStartedAt(pid int32) string {
+	stat, err := readProcStat(pid)
+	if err != nil {
+		return ""
+	}
+
+	bootTime, err := host.BootTime()
+	if err != nil {
+		return ""
+	}
+
+	return time.Unix(int64(bootTime+stat.startTime/clockTicks), 0).UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,160 @@
+package collectors
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/gogo/protobuf/types"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// containerdStandIn holds the containers and tasks per namespace served to `ContainerdRuntime`
+type containerdStandIn struct {
+	containers map[string][]containersapi.Container
+	tasks      map[string][]*task.Process
+}
+
+// the services only implement the calls issued by `ContainerdRuntime`
+type containerdNamespaces struct {
+	namespacesapi.NamespacesServer
+	*containerdStandIn
+}
+
+type containerdContainers struct {
+	containersapi.ContainersServer
+	*containerdStandIn
+}
+
+type containerdTasks struct {
+	tasksapi.TasksServer
+	*containerdStandIn
+}
+
+func (c containerdNamespaces) List(ctx context.Context, req *namespacesapi.ListNamespacesRequest) (*namespacesapi.ListNamespacesResponse, error) {
+	res := &namespacesapi.ListNamespacesResponse{}
+	for name := range c.containers {
+		res.Namespaces = append(res.Namespaces, namespacesapi.Namespace{Name: name})
+	}
+
+	return res, nil
+}
+
+func (c containerdContainers) List(ctx context.Context, req *containersapi.ListContainersRequest) (*containersapi.ListContainersResponse, error) {
+	namespace, _ := namespaces.Namespace(ctx)
+
+	return &containersapi.ListContainersResponse{Containers: c.containers[namespace]}, nil
+}
+
+func (c containerdTasks) List(ctx context.Context, req *tasksapi.ListTasksRequest) (*tasksapi.ListTasksResponse, error) {
+	namespace, _ := namespaces.Namespace(ctx)
+
+	return &tasksapi.ListTasksResponse{Tasks: c.tasks[namespace]}, nil
+}
+
+func TestContainerdRuntime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "containerd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(contents string, parts ...string) {
+		path := filepath.Join(append([]string{dir}, parts...)...)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+
+	standIn := &containerdStandIn{
+		containers: map[string][]containersapi.Container{
+			"k8s.io": {
+				{
+					ID:        "abc123",
+					Image:     "docker.io/library/php:8.0-fpm",
+					CreatedAt: time.Unix(1600000000, 0),
+					Spec: &types.Any{Value: []byte(`{
+						"process": {"args": ["php-fpm", "-F"]},
+						"annotations": {"io.kubernetes.cri.container-name": "app"}
+					}`)},
+				},
+				// pod sandboxes run the pause container
+				{
+					ID:     "sandbox1",
+					Labels: map[string]string{"io.cri-containerd.kind": "sandbox"},
+					Spec:   &types.Any{Value: []byte(`{"annotations": {"io.kubernetes.cri.container-type": "sandbox"}}`)},
+				},
+				// created but never started
+				{ID: "stopped1"},
+			},
+			// Docker's containers are reported by the Docker runtime
+			"moby": {{ID: "def456"}},
+		},
+		tasks: map[string][]*task.Process{
+			"k8s.io": {
+				{ID: "abc123", Pid: 42, Status: task.StatusRunning},
+				{ID: "sandbox1", Pid: 41, Status: task.StatusRunning},
+				{ID: "stopped1", Status: task.StatusCreated},
+			},
+			"moby": {{ID: "def456", Pid: 43, Status: task.StatusRunning}},
+		},
+	}
+
+	socket := filepath.Join(dir, "containerd.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+	server := grpc.NewServer()
+	namespacesapi.RegisterNamespacesServer(server, containerdNamespaces{containerdStandIn: standIn})
+	containersapi.RegisterContainersServer(server, containerdContainers{containerdStandIn: standIn})
+	tasksapi.RegisterTasksServer(server, containerdTasks{containerdStandIn: standIn})
+	go server.Serve(listener)
+	defer server.Stop()
+
+	write("0::/kubepods/abc123\n", "proc", "42", "cgroup")
+	write("0::/kubepods/sandbox1\n", "proc", "41", "cgroup")
+	write("0::/system.slice/docker-def456.scope\n", "proc", "43", "cgroup")
+	write("", "sys", "fs", "cgroup", "cgroup.controllers")
+	cgroup := []string{"sys", "fs", "cgroup", "kubepods", "abc123"}
+	write("usage_usec 1000000\nuser_usec 600000\n", append(cgroup, "cpu.stat")...)
+	write("104857600\n", append(cgroup, "memory.current")...)
+	write("file 1024\ninactive_file 4194304\n", append(cgroup, "memory.stat")...)
+	write("209715200\n", append(cgroup, "memory.max")...)
+	write("5\n", append(cgroup, "pids.current")...)
+
+	os.Setenv("HOST_PROC", filepath.Join(dir, "proc"))
+	defer os.Unsetenv("HOST_PROC")
+	os.Setenv("HOST_SYS", filepath.Join(dir, "sys"))
+	defer os.Unsetenv("HOST_SYS")
+
+	runtime := NewContainerdRuntime(socket)
+	defer runtime.Close()
+
+	containers, err := runtime.FetchContainers()
+	assert.NoError(t, err)
+	assert.Len(t, containers, 1)
+
+	cont := containers[0]
+	assert.Equal(t, "abc123", cont.ID)
+	assert.Equal(t, "app", cont.Name)
+	assert.Equal(t, "docker.io/library/php:8.0-fpm", cont.Image)
+	assert.Equal(t, "php-fpm -F", cont.Command)
+	assert.Equal(t, int64(1600000000), cont.CreatedAt)
+	assert.Equal(t, runtimeContainerd, cont.Type)
+	assert.Equal(t, uint64(5), cont.PIDs)
+	assert.Equal(t, float64(100*1024*1024-4*1024*1024), cont.MemoryCurrent)
+	assert.Equal(t, float64(200*1024*1024), cont.MemoryTotal)
+	assert.Equal(t, float64(0), cont.CPUUsedPercentage)
+
+	write("usage_usec 2000000\n", append(cgroup, "cpu.stat")...)
+
+	containers, err = runtime.FetchContainers()
+	assert.NoError(t, err)
+	assert.True(t, containers[0].CPUUsedPercentage > 0)
+}
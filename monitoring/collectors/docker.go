@@ -2,21 +2,33 @@ package collectors
 
 import (
 	"context"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 
 	docker "github.com/larashed/agent-go/docker"
 	metrics "github.com/larashed/agent-go/monitoring/metrics"
 )
 
+// healthcheck output is kept short, it's often a full HTTP response
+const healthOutputLimit = 256
+
 // DockerClient holds the docker API client
 type DockerClient struct {
-	client *client.Client
+	client  *client.Client
+	runtime string
+	stats   *docker.StatsManager
+	volumes *VolumeSizer
+
+	// cgroupStats reads usage from container cgroups instead of streaming it from the API
+	cgroupStats bool
+	sampler     *cgroupSampler
+	mutex       sync.Mutex
 }
 
 // NewDockerClient creates a docker API client instance
@@ -26,16 +38,61 @@ func NewDockerClient() (*DockerClient, error) {
 		return nil, errors.Wrap(err, "Failed to create Docker API client")
 	}
 
+	return newDockerClient(apiClient, runtimeDocker), nil
+}
+
+// newDockerClient wraps a Docker Engine API client, `runtime` tells Docker and Podman apart
+func newDockerClient(apiClient *client.Client, runtime string) *DockerClient {
 	return &DockerClient{
-		client: apiClient,
-	}, nil
+		client:  apiClient,
+		runtime: runtime,
+		stats:   docker.NewStatsManager(apiClient),
+		sampler: newCgroupSampler(),
+	}
 }
 
-// FetchContainers fetches docker containers with metrics and volumes
-func (dc *DockerClient) FetchContainers() (collectedContainers []metrics.Container, err error) {
-	containersWithStats, err := docker.GetStats(dc.client)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to fetch item stats")
+// Name returns the runtime name
+func (dc *DockerClient) Name() string {
+	return dc.runtime
+}
+
+// Close stops streaming container stats
+func (dc *DockerClient) Close() {
+	dc.stats.Stop()
+}
+
+// FetchContainers fetches docker containers with metrics and volumes.
+// Running Docker containers are still reported from their cgroups when the API can't be reached.
+func (dc *DockerClient) FetchContainers() ([]metrics.Container, error) {
+	dc.mutex.Lock()
+	defer dc.mutex.Unlock()
+
+	dc.sampler.begin()
+	defer dc.sampler.end()
+
+	containers, err := dc.fetchContainers()
+	if err == nil || dc.runtime != runtimeDocker {
+		return containers, err
+	}
+
+	cgroups := dockerCgroups()
+	if len(cgroups) == 0 {
+		return nil, err
+	}
+	log.Trace().Err(err).Msg("Docker API unavailable, reading container cgroups")
+
+	return dc.cgroupContainers(cgroups), nil
+}
+
+func (dc *DockerClient) fetchContainers() (collectedContainers []metrics.Container, err error) {
+	var containersWithStats []docker.StatsEntry
+	if !dc.cgroupStats {
+		// stats are streamed in the background after the first call, later calls only take a snapshot
+		err = dc.stats.Start()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to fetch item stats")
+		}
+		containersWithStats = dc.stats.Snapshot()
 	}
 
 	containerList, err := dc.client.ContainerList(context.Background(), types.ContainerListOptions{Size: true})
@@ -47,26 +104,40 @@ func (dc *DockerClient) FetchContainers() (collectedContainers []metrics.Contain
 		cont := metrics.Container{}
 		cont.ID = item.ID
 		cont.Name = strings.Join(item.Names, ";")
-		cont.Type = "docker"
+		cont.Type = dc.runtime
 		cont.Command = item.Command
 		cont.Image = item.Image
 		cont.CreatedAt = item.Created
 		containerState, err := dc.client.ContainerInspect(context.Background(), item.ID)
 		if err == nil {
 			cont.StartedAt = containerState.State.StartedAt
+			cont.RestartCount = containerState.RestartCount
+			cont.OOMKilled = containerState.State.OOMKilled
+			cont.ExitCode = containerState.State.ExitCode
+			cont.Health = containerHealth(containerState.State.Health)
+
+			if dc.cgroupStats && containerState.State.Pid > 0 {
+				stats, err := readCgroupStats(containerState.State.Pid)
+				if err == nil {
+					dc.sampler.fill(&cont, stats)
+				} else {
+					log.Trace().Err(err).Str("container", item.ID).Msg("Failed to read container cgroup")
+				}
+			}
 		}
 		cont.State = item.State
 		cont.Status = item.Status
 		cont.SizeContainer = item.SizeRootFs
 		cont.SizeAdded = item.SizeRw
 		cont.Labels = item.Labels
+		tagCompose(&cont, item.Labels)
 		cont.NetworkName = item.HostConfig.NetworkMode
 		if item.NetworkSettings.Networks[cont.NetworkName] != nil {
 			cont.IPAddress = item.NetworkSettings.Networks[cont.NetworkName].IPAddress
 		}
 
 		for _, mount := range item.Mounts {
-			cont.Volumes = append(cont.Volumes, metrics.Volume{
+			volume := metrics.Volume{
 				Type:        string(mount.Type),
 				Name:        mount.Name,
 				Source:      mount.Source,
@@ -74,8 +145,13 @@ func (dc *DockerClient) FetchContainers() (collectedContainers []metrics.Contain
 				Driver:      mount.Driver,
 				Mode:        mount.Mode,
 				RW:          mount.RW,
-				Size:        volumeSize(mount.Source),
-			})
+			}
+
+			if dc.volumes != nil {
+				dc.volumes.Fill(&volume)
+			}
+
+			cont.Volumes = append(cont.Volumes, volume)
 		}
 
 		for _, port := range item.Ports {
@@ -101,6 +177,9 @@ func (dc *DockerClient) FetchContainers() (collectedContainers []metrics.Contain
 
 			cont.NetworkInbound = cws.NetworkRx
 			cont.NetworkOutbound = cws.NetworkTx
+
+			cont.BlockRead = cws.BlockRead
+			cont.BlockWrite = cws.BlockWrite
 		}
 
 		collectedContainers = append(collectedContainers, cont)
@@ -109,22 +188,52 @@ func (dc *DockerClient) FetchContainers() (collectedContainers []metrics.Contain
 	return collectedContainers, nil
 }
 
-func volumeSize(path string) int64 {
-	var size int64
-	path = strings.Replace(path, "/host_mnt", "", 1)
-	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+// cgroupContainers reports running containers found by `dockerCgroups`, only their id and usage are known
+func (dc *DockerClient) cgroupContainers(cgroups map[string]string) []metrics.Container {
+	containers := make([]metrics.Container, 0, len(cgroups))
+	for id, path := range cgroups {
+		stats, err := readCgroupPathStats(path)
 		if err != nil {
-			return err
+			continue
 		}
-		if !info.IsDir() {
-			size += info.Size()
+
+		cont := metrics.Container{
+			ID:    id,
+			Name:  id[:12],
+			Type:  dc.runtime,
+			State: "running",
 		}
-		return err
+		dc.sampler.fill(&cont, stats)
+		containers = append(containers, cont)
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].ID < containers[j].ID
 	})
 
-	if err != nil {
-		return 0
+	return containers
+}
+
+// containerHealth summarises healthcheck results, nil when no healthcheck is configured
+func containerHealth(health *types.Health) *metrics.ContainerHealth {
+	if health == nil {
+		return nil
+	}
+
+	h := &metrics.ContainerHealth{
+		Status:        health.Status,
+		FailingStreak: health.FailingStreak,
+	}
+
+	for _, result := range health.Log {
+		if result.ExitCode != 0 {
+			h.RecentFailures++
+		}
+	}
+
+	if len(health.Log) > 0 {
+		h.LastOutput = truncate(strings.TrimSpace(health.Log[len(health.Log)-1].Output), healthOutputLimit)
 	}
 
-	return size
+	return h
 }
@@ -0,0 +1,133 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/larashed/agent-go/monitoring/buckets"
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// delay before subscribing again after the event stream fails, e.g. when dockerd restarts
+const dockerEventsReconnectInterval = 10 * time.Second
+
+var forwardedContainerActions = []string{"create", "start", "die", "kill", "oom", "health_status", "restart"}
+
+// DockerEventWatcher forwards container lifecycle events
+type DockerEventWatcher struct {
+	client   client.APIClient
+	bucket   *buckets.ContainerEventBucket
+	hostname string
+	ctx      context.Context
+	stop     context.CancelFunc
+}
+
+// NewDockerEventWatcher creates a new instance of `DockerEventWatcher`, failing when the Docker daemon can't be reached
+func NewDockerEventWatcher(bucket *buckets.ContainerEventBucket, hostname string) (*DockerEventWatcher, error) {
+	dockerClient, err := NewDockerClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// creating the client doesn't connect, hosts without Docker would keep resubscribing
+	ctx, cancel := context.WithTimeout(context.Background(), runtimeDetectTimeout)
+	defer cancel()
+	if _, err := dockerClient.client.Ping(ctx); err != nil {
+		dockerClient.client.Close()
+		return nil, errors.Wrap(err, "Failed to reach the Docker daemon")
+	}
+
+	return newDockerEventWatcher(dockerClient.client, bucket, hostname), nil
+}
+
+func newDockerEventWatcher(apiClient client.APIClient, bucket *buckets.ContainerEventBucket, hostname string) *DockerEventWatcher {
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &DockerEventWatcher{
+		client:   apiClient,
+		bucket:   bucket,
+		hostname: hostname,
+		ctx:      ctx,
+		stop:     stop,
+	}
+}
+
+// Start watching container events until stopped
+func (w *DockerEventWatcher) Start() {
+	f := filters.NewArgs()
+	f.Add("type", "container")
+	for _, action := range forwardedContainerActions {
+		f.Add("event", action)
+	}
+
+	since := time.Now()
+	for {
+		// events missed while disconnected are replayed by the daemon
+		eventq, errq := w.client.Events(w.ctx, types.EventsOptions{
+			Filters: f,
+			Since:   fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()),
+		})
+
+	stream:
+		for {
+			select {
+			case <-w.ctx.Done():
+				return
+			case event := <-eventq:
+				w.bucket.Add(containerEvent(event, w.hostname))
+				since = time.Unix(0, event.TimeNano+1)
+			case err := <-errq:
+				log.Trace().Err(err).Msg("Docker event stream closed")
+				break stream
+			}
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-time.After(dockerEventsReconnectInterval):
+		}
+	}
+}
+
+// Stop watching container events
+func (w *DockerEventWatcher) Stop() {
+	w.stop()
+}
+
+func containerEvent(event events.Message, hostname string) metrics.ContainerEvent {
+	action := event.Action
+	attributes := event.Actor.Attributes
+
+	containerEvent := metrics.ContainerEvent{
+		Hostname: hostname,
+		ID:       event.Actor.ID,
+		Name:     attributes["name"],
+		Image:    attributes["image"],
+		Action:   action,
+		Signal:   attributes["signal"],
+		Time:     time.Unix(0, event.TimeNano).UTC().Format(time.RFC3339Nano),
+	}
+
+	// health changes are reported as e.g. "health_status: unhealthy"
+	if strings.HasPrefix(action, "health_status:") {
+		containerEvent.Action = "health_status"
+		containerEvent.HealthStatus = strings.TrimSpace(strings.TrimPrefix(action, "health_status:"))
+	}
+
+	if exitCode, err := strconv.Atoi(attributes["exitCode"]); err == nil {
+		containerEvent.ExitCode = &exitCode
+	}
+
+	return containerEvent
+}
@@ -0,0 +1,72 @@
+package collectors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/monitoring/buckets"
+)
+
+type dockerEventsStandIn struct {
+	client.APIClient
+
+	options chan types.EventsOptions
+	events  chan events.Message
+	errs    chan error
+}
+
+func (d *dockerEventsStandIn) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	d.options <- options
+	return d.events, d.errs
+}
+
+func TestDockerEventWatcher(t *testing.T) {
+	standIn := &dockerEventsStandIn{
+		options: make(chan types.EventsOptions, 1),
+		events:  make(chan events.Message),
+		errs:    make(chan error),
+	}
+
+	bucket := buckets.NewContainerEventBucket(10)
+	watcher := newDockerEventWatcher(standIn, bucket, "web-1")
+	go watcher.Start()
+	defer watcher.Stop()
+
+	options := <-standIn.options
+	assert.Equal(t, []string{"container"}, options.Filters.Get("type"))
+	assert.Contains(t, options.Filters.Get("event"), "oom")
+	assert.NotEmpty(t, options.Since)
+
+	at := time.Date(2020, 12, 1, 10, 0, 0, 5, time.UTC)
+	standIn.events <- events.Message{
+		Action:   "die",
+		Actor:    events.Actor{ID: "abc", Attributes: map[string]string{"name": "app", "image": "php:7.4-fpm", "exitCode": "137"}},
+		TimeNano: at.UnixNano(),
+	}
+	standIn.events <- events.Message{
+		Action:   "health_status: unhealthy",
+		Actor:    events.Actor{ID: "abc", Attributes: map[string]string{"name": "app"}},
+		TimeNano: at.UnixNano(),
+	}
+
+	assert.Eventually(t, func() bool {
+		return bucket.Count() == 2
+	}, time.Second, 10*time.Millisecond)
+
+	received := bucket.Extract()
+	assert.Equal(t, "web-1", received[0].Hostname)
+	assert.Equal(t, "die", received[0].Action)
+	assert.Equal(t, "php:7.4-fpm", received[0].Image)
+	assert.Equal(t, 137, *received[0].ExitCode)
+	assert.Equal(t, "2020-12-01T10:00:00.000000005Z", received[0].Time)
+
+	assert.Equal(t, "health_status", received[1].Action)
+	assert.Equal(t, "unhealthy", received[1].HealthStatus)
+	assert.Nil(t, received[1].ExitCode)
+}
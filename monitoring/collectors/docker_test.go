@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDocker(t *testing.T) {
@@ -22,4 +24,23 @@ func TestDocker(t *testing.T) {
 		panic(err)
 	}
 	spew.Dump(containers)
-}
\ No newline at end of file
+}
+
+func TestContainerHealth(t *testing.T) {
+	assert.Nil(t, containerHealth(nil))
+
+	health := containerHealth(&types.Health{
+		Status:        "healthy",
+		FailingStreak: 0,
+		Log: []*types.HealthcheckResult{
+			{ExitCode: 1, Output: "curl: (7) Failed to connect"},
+			{ExitCode: 0, Output: "ok"},
+			{ExitCode: 1, Output: "curl: (28) Operation timed out"},
+			{ExitCode: 0, Output: " ok\n"},
+		},
+	})
+
+	assert.Equal(t, "healthy", health.Status)
+	assert.Equal(t, 2, health.RecentFailures)
+	assert.Equal(t, "ok", health.LastOutput)
+}
@@ -0,0 +1,145 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// a minimal FastCGI client, just enough to request PHP-FPM status pages
+// https://fastcgi-archives.github.io/FastCGI_Specification.html
+
+const (
+	fcgiVersion       = 1
+	fcgiRequestID     = 1
+	fcgiRoleResponder = 1
+
+	fcgiBeginRequest = 1
+	fcgiEndRequest   = 3
+	fcgiParams       = 4
+	fcgiStdin        = 5
+	fcgiStdout       = 6
+	fcgiStderr       = 7
+)
+
+type fcgiHeader struct {
+	Version       uint8
+	Type          uint8
+	RequestID     uint16
+	ContentLength uint16
+	PaddingLength uint8
+	Reserved      uint8
+}
+
+// fastCGIGet performs a GET request against a FastCGI responder and returns the response body
+func fastCGIGet(network, address string, params map[string]string, timeout time.Duration) ([]byte, error) {
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	var request bytes.Buffer
+	writeFCGIRecord(&request, fcgiBeginRequest, []byte{0, fcgiRoleResponder, 0, 0, 0, 0, 0, 0})
+	writeFCGIRecord(&request, fcgiParams, encodeFCGIParams(params))
+	writeFCGIRecord(&request, fcgiParams, nil)
+	writeFCGIRecord(&request, fcgiStdin, nil)
+
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	reader := bufio.NewReader(conn)
+	for {
+		header := fcgiHeader{}
+		if err := binary.Read(reader, binary.BigEndian, &header); err != nil {
+			return nil, errors.Wrap(err, "Failed to read FastCGI response")
+		}
+
+		content := make([]byte, int(header.ContentLength)+int(header.PaddingLength))
+		if _, err := io.ReadFull(reader, content); err != nil {
+			return nil, errors.Wrap(err, "Failed to read FastCGI response")
+		}
+		content = content[:header.ContentLength]
+
+		switch header.Type {
+		case fcgiStdout:
+			stdout.Write(content)
+		case fcgiStderr:
+			stderr.Write(content)
+		case fcgiEndRequest:
+			return parseCGIResponse(stdout.Bytes(), stderr.String())
+		}
+	}
+}
+
+func writeFCGIRecord(w *bytes.Buffer, recordType uint8, content []byte) {
+	padding := (8 - len(content)%8) % 8
+	_ = binary.Write(w, binary.BigEndian, fcgiHeader{
+		Version:       fcgiVersion,
+		Type:          recordType,
+		RequestID:     fcgiRequestID,
+		ContentLength: uint16(len(content)),
+		PaddingLength: uint8(padding),
+	})
+	w.Write(content)
+	w.Write(make([]byte, padding))
+}
+
+func encodeFCGIParams(params map[string]string) []byte {
+	var b bytes.Buffer
+	for name, value := range params {
+		writeFCGILength(&b, len(name))
+		writeFCGILength(&b, len(value))
+		b.WriteString(name)
+		b.WriteString(value)
+	}
+
+	return b.Bytes()
+}
+
+func writeFCGILength(b *bytes.Buffer, length int) {
+	if length < 128 {
+		b.WriteByte(byte(length))
+		return
+	}
+
+	_ = binary.Write(b, binary.BigEndian, uint32(length)|1<<31)
+}
+
+// parseCGIResponse strips CGI headers and checks the response status
+func parseCGIResponse(response []byte, stderr string) ([]byte, error) {
+	reader := textproto.NewReader(bufio.NewReader(bytes.NewReader(response)))
+	headers, err := reader.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "Failed to parse FastCGI response headers")
+	}
+
+	if status := headers.Get("Status"); len(status) > 0 {
+		code, _ := strconv.Atoi(strings.Fields(status)[0])
+		if code != 200 {
+			return nil, errors.Errorf("FastCGI responder returned %q %s", status, strings.TrimSpace(stderr))
+		}
+	}
+
+	body, err := ioutil.ReadAll(reader.R)
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
@@ -0,0 +1,100 @@
+package collectors
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// horizonInactive is reported for masters and supervisors whose heartbeat hash expired
+const horizonInactive = "inactive"
+
+// Horizon reads Laravel Horizon supervisor state and job counts
+func (c *RedisCollector) Horizon() (*metrics.Horizon, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	prefix := c.config.HorizonPrefix
+	horizon := &metrics.Horizon{}
+
+	masters, err := horizonMembers(conn, prefix, "masters", "master:")
+	if err != nil {
+		return nil, err
+	}
+	for name, fields := range masters {
+		master := metrics.HorizonMaster{Name: name, Status: horizonInactive}
+		if fields != nil {
+			master.PID = fields["pid"]
+			master.Status = fields["status"]
+		}
+		horizon.Masters = append(horizon.Masters, master)
+	}
+
+	supervisors, err := horizonMembers(conn, prefix, "supervisors", "supervisor:")
+	if err != nil {
+		return nil, err
+	}
+	for name, fields := range supervisors {
+		supervisor := metrics.HorizonSupervisor{Name: name, Status: horizonInactive}
+		if fields != nil {
+			supervisor.Master = fields["master"]
+			supervisor.PID = fields["pid"]
+			supervisor.Status = fields["status"]
+			_ = json.Unmarshal([]byte(fields["processes"]), &supervisor.Processes)
+		}
+		horizon.Supervisors = append(horizon.Supervisors, supervisor)
+	}
+
+	for key, count := range map[string]*uint64{
+		"pending_jobs":   &horizon.PendingJobs,
+		"completed_jobs": &horizon.CompletedJobs,
+		"recent_jobs":    &horizon.RecentJobs,
+		"failed_jobs":    &horizon.FailedJobs,
+	} {
+		*count, err = redis.Uint64(conn.Do("ZCARD", prefix+key))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to count Horizon %s", key)
+		}
+	}
+
+	sortHorizon(horizon)
+
+	return horizon, nil
+}
+
+// horizonMembers reads the members of a Horizon index sorted set and their hashes.
+// Hashes expire when their process stops sending heartbeats, those map to nil.
+func horizonMembers(conn redis.Conn, prefix, index, hashPrefix string) (map[string]map[string]string, error) {
+	names, err := redis.Strings(conn.Do("ZRANGE", prefix+index, 0, -1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to fetch Horizon %s", index)
+	}
+
+	members := make(map[string]map[string]string, len(names))
+	for _, name := range names {
+		fields, err := redis.StringMap(conn.Do("HGETALL", prefix+hashPrefix+name))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to fetch Horizon %s%s", hashPrefix, name)
+		}
+
+		if len(fields) == 0 {
+			fields = nil
+		}
+		members[name] = fields
+	}
+
+	return members, nil
+}
+
+func sortHorizon(horizon *metrics.Horizon) {
+	sort.Slice(horizon.Masters, func(i, j int) bool {
+		return horizon.Masters[i].Name < horizon.Masters[j].Name
+	})
+	sort.Slice(horizon.Supervisors, func(i, j int) bool {
+		return horizon.Supervisors[i].Name < horizon.Supervisors[j].Name
+	})
+}
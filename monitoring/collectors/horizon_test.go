@@ -0,0 +1,49 @@
+package collectors
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHorizon(t *testing.T) {
+	address, stop := redisStandIn(t, func(args []string) interface{} {
+		switch strings.Join(args, " ") {
+		case "ZRANGE laravel_horizon:masters 0 -1":
+			return []interface{}{"host-a1b2", "host-dead"}
+		case "HGETALL laravel_horizon:master:host-a1b2":
+			return []interface{}{"pid", "1234", "status", "running"}
+		case "ZRANGE laravel_horizon:supervisors 0 -1":
+			return []interface{}{"host-a1b2:supervisor-1"}
+		case "HGETALL laravel_horizon:supervisor:host-a1b2:supervisor-1":
+			return []interface{}{"master", "host-a1b2", "pid", "1240", "status", "paused", "processes", `{"redis:default":3}`}
+		case "ZCARD laravel_horizon:failed_jobs":
+			return 4
+		case "ZCARD laravel_horizon:recent_jobs":
+			return 100
+		case "HGETALL laravel_horizon:master:host-dead":
+			return []interface{}{}
+		}
+
+		return 0
+	})
+	defer stop()
+
+	collector := NewRedisCollector(RedisConfig{Address: address, HorizonPrefix: "laravel_horizon:"})
+	horizon, err := collector.Horizon()
+	assert.NoError(t, err)
+
+	assert.Len(t, horizon.Masters, 2)
+	assert.Equal(t, "1234", horizon.Masters[0].PID)
+	assert.Equal(t, "running", horizon.Masters[0].Status)
+	assert.Equal(t, "inactive", horizon.Masters[1].Status)
+
+	assert.Len(t, horizon.Supervisors, 1)
+	assert.Equal(t, "paused", horizon.Supervisors[0].Status)
+	assert.Equal(t, map[string]int{"redis:default": 3}, horizon.Supervisors[0].Processes)
+
+	assert.Equal(t, uint64(4), horizon.FailedJobs)
+	assert.Equal(t, uint64(100), horizon.RecentJobs)
+	assert.Equal(t, uint64(0), horizon.PendingJobs)
+}
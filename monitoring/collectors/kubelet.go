@@ -0,0 +1,173 @@
+package collectors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const (
+	kubeletTimeout = 5 * time.Second
+
+	// DefaultKubeletTokenFile is the service account token mounted into every pod
+	DefaultKubeletTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	// DefaultKubeletCAFile is the cluster CA mounted into every pod
+	DefaultKubeletCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// kubeletPodList is the part of the kubelet `/pods` response the agent reads
+type kubeletPodList struct {
+	Items []kubeletPod `json:"items"`
+}
+
+type kubeletPod struct {
+	Metadata struct {
+		Name            string            `json:"name"`
+		Namespace       string            `json:"namespace"`
+		UID             string            `json:"uid"`
+		Labels          map[string]string `json:"labels"`
+		OwnerReferences []struct {
+			Kind       string `json:"kind"`
+			Name       string `json:"name"`
+			Controller bool   `json:"controller"`
+		} `json:"ownerReferences"`
+	} `json:"metadata"`
+	Status struct {
+		ContainerStatuses     []kubeletContainerStatus `json:"containerStatuses"`
+		InitContainerStatuses []kubeletContainerStatus `json:"initContainerStatuses"`
+	} `json:"status"`
+}
+
+type kubeletContainerStatus struct {
+	Name        string `json:"name"`
+	ContainerID string `json:"containerID"` // <runtime>://<id>
+}
+
+// KubeletConfig holds kubelet connection settings
+type KubeletConfig struct {
+	URL         string
+	TokenFile   string
+	CAFile      string
+	InsecureTLS bool // Skip verifying the kubelet's serving certificate, often self-signed
+}
+
+// KubeletClient tags containers with the pod they belong to, using the node's kubelet
+type KubeletClient struct {
+	url       string
+	tokenFile string
+	client    http.Client
+}
+
+// NewKubeletClient creates a new instance of `KubeletClient`
+func NewKubeletClient(cfg KubeletConfig) *KubeletClient {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureTLS} //nolint:gosec
+	if contents, err := ioutil.ReadFile(cfg.CAFile); err == nil && !cfg.InsecureTLS {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		tlsConfig.RootCAs.AppendCertsFromPEM(contents)
+	}
+
+	return &KubeletClient{
+		url:       strings.TrimSuffix(cfg.URL, "/"),
+		tokenFile: cfg.TokenFile,
+		client: http.Client{
+			Timeout:   kubeletTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}
+}
+
+// Tag sets `Container.Kubernetes` on containers run by a pod on this node
+func (k *KubeletClient) Tag(containers []metrics.Container) error {
+	if len(containers) == 0 {
+		return nil
+	}
+
+	pods, err := k.pods()
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*metrics.KubernetesPod)
+	for _, pod := range pods {
+		for _, status := range append(pod.Status.ContainerStatuses, pod.Status.InitContainerStatuses...) {
+			parts := strings.SplitN(status.ContainerID, "://", 2)
+			if len(parts) != 2 {
+				continue
+			}
+
+			tag := podMetadata(pod)
+			tag.Container = status.Name
+			byID[parts[1]] = tag
+		}
+	}
+
+	for i := range containers {
+		containers[i].Kubernetes = byID[containers[i].ID]
+	}
+
+	return nil
+}
+
+// pods lists pods scheduled on this node
+func (k *KubeletClient) pods() ([]kubeletPod, error) {
+	req, err := http.NewRequest(http.MethodGet, k.url+"/pods", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the token is rotated by the kubelet, read it on every request
+	if token, err := ioutil.ReadFile(k.tokenFile); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to reach kubelet")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Kubelet returned %s", resp.Status)
+	}
+
+	var list kubeletPodList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode kubelet pods")
+	}
+
+	return list.Items, nil
+}
+
+// podMetadata resolves the pod's controller, Deployments are found through the
+// `pod-template-hash` suffix of the ReplicaSet name
+func podMetadata(pod kubeletPod) *metrics.KubernetesPod {
+	tag := &metrics.KubernetesPod{
+		Namespace: pod.Metadata.Namespace,
+		Pod:       pod.Metadata.Name,
+		PodUID:    pod.Metadata.UID,
+	}
+
+	for _, owner := range pod.Metadata.OwnerReferences {
+		if !owner.Controller {
+			continue
+		}
+
+		tag.OwnerKind = owner.Kind
+		tag.OwnerName = owner.Name
+
+		hash := pod.Metadata.Labels["pod-template-hash"]
+		if owner.Kind == "ReplicaSet" && len(hash) > 0 && strings.HasSuffix(owner.Name, "-"+hash) {
+			tag.Deployment = strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+
+	return tag
+}
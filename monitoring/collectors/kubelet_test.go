@@ -0,0 +1,89 @@
+package collectors
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const kubeletPods = `{
+	"kind": "PodList",
+	"items": [
+		{
+			"metadata": {
+				"name": "web-7d4b9c8f6d-x2x9z",
+				"namespace": "shop",
+				"uid": "0b5c7b36-1d0e-4d6a-9a48-39d8f0b1a2c3",
+				"labels": {"app": "web", "pod-template-hash": "7d4b9c8f6d"},
+				"ownerReferences": [{"kind": "ReplicaSet", "name": "web-7d4b9c8f6d", "controller": true}]
+			},
+			"status": {
+				"initContainerStatuses": [{"name": "migrate", "containerID": "containerd://init1"}],
+				"containerStatuses": [
+					{"name": "php-fpm", "containerID": "containerd://abc123"},
+					{"name": "pending"}
+				]
+			}
+		},
+		{
+			"metadata": {
+				"name": "horizon-0",
+				"namespace": "shop",
+				"ownerReferences": [{"kind": "StatefulSet", "name": "horizon", "controller": true}]
+			},
+			"status": {"containerStatuses": [{"name": "horizon", "containerID": "docker://def456"}]}
+		}
+	]
+}`
+
+func TestKubeletClient(t *testing.T) {
+	kubelet := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pods" || r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte(kubeletPods))
+	}))
+	defer kubelet.Close()
+
+	token, err := ioutil.TempFile("", "token")
+	assert.NoError(t, err)
+	defer os.Remove(token.Name())
+	_, _ = token.WriteString("token\n")
+	token.Close()
+
+	containers := []metrics.Container{{ID: "abc123"}, {ID: "def456"}, {ID: "init1"}, {ID: "unknown"}}
+
+	// the stand-in's certificate isn't signed by the cluster CA
+	client := NewKubeletClient(KubeletConfig{URL: kubelet.URL, TokenFile: token.Name()})
+	assert.Error(t, client.Tag(containers))
+
+	client = NewKubeletClient(KubeletConfig{URL: kubelet.URL + "/", TokenFile: token.Name(), InsecureTLS: true})
+	assert.NoError(t, client.Tag(containers))
+
+	assert.Equal(t, &metrics.KubernetesPod{
+		Namespace:  "shop",
+		Pod:        "web-7d4b9c8f6d-x2x9z",
+		PodUID:     "0b5c7b36-1d0e-4d6a-9a48-39d8f0b1a2c3",
+		Container:  "php-fpm",
+		OwnerKind:  "ReplicaSet",
+		OwnerName:  "web-7d4b9c8f6d",
+		Deployment: "web",
+	}, containers[0].Kubernetes)
+
+	assert.Equal(t, "horizon", containers[1].Kubernetes.Container)
+	assert.Equal(t, "StatefulSet", containers[1].Kubernetes.OwnerKind)
+	assert.Equal(t, "", containers[1].Kubernetes.Deployment)
+	assert.Equal(t, "migrate", containers[2].Kubernetes.Container)
+	assert.Nil(t, containers[3].Kubernetes)
+
+	client = NewKubeletClient(KubeletConfig{URL: kubelet.URL, TokenFile: "/nonexistent", InsecureTLS: true})
+	assert.Error(t, client.Tag(containers))
+}
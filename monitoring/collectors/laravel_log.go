@@ -0,0 +1,376 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/larashed/agent-go/monitoring/buckets"
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const (
+	logPollInterval   = 2 * time.Second
+	logMessageLimit   = 8 * 1024
+	laravelLogPattern = "storage/logs/laravel*.log"
+)
+
+var (
+	// [2021-01-05 12:34:56] production.ERROR: Message {"exception":"..."}
+	monologLine = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2}[T ][^\]]+)\] ([^\s.]+)\.([A-Z]+): (.*)$`)
+
+	// levels forwarded as sampled entries
+	forwardedLogLevels = map[string]bool{
+		"ERROR":     true,
+		"CRITICAL":  true,
+		"ALERT":     true,
+		"EMERGENCY": true,
+	}
+)
+
+// tailedFile holds the read position of a log file, persisted across restarts. The file stays open
+// between polls, so lines written right before it's rotated away are still read.
+type tailedFile struct {
+	Offset int64  `json:"offset"`
+	Inode  uint64 `json:"inode"`
+
+	app  string
+	file *os.File
+	// the last entry read, its continuation lines may only arrive with the next poll
+	entry *metrics.LogEntry
+}
+
+// LogTailerConfig holds Laravel log tailing settings
+type LogTailerConfig struct {
+	AppPaths       []string
+	StateFile      string
+	SampleLimit    int
+	Environment    string
+	ReportInterval time.Duration
+}
+
+// LogTailer follows Laravel log files, counts entries per level and forwards
+// sampled errors as `log` app metrics
+type LogTailer struct {
+	config  LogTailerConfig
+	bucket  *buckets.AppMetricBucket
+	files   map[string]*tailedFile
+	reports map[string]*metrics.LogReport
+	started bool
+	saved   []byte
+	stop    chan struct{}
+}
+
+// NewLogTailer creates a new instance of `LogTailer`
+func NewLogTailer(cfg LogTailerConfig, bucket *buckets.AppMetricBucket) *LogTailer {
+	tailer := &LogTailer{
+		config:  cfg,
+		bucket:  bucket,
+		files:   make(map[string]*tailedFile),
+		reports: make(map[string]*metrics.LogReport),
+		stop:    make(chan struct{}),
+	}
+
+	if err := tailer.loadState(); err != nil && !os.IsNotExist(errors.Cause(err)) {
+		log.Warn().Err(err).Msg("Failed to load log offsets, tailing from the end of files")
+	}
+
+	return tailer
+}
+
+// Start tailing log files
+func (lt *LogTailer) Start() {
+	pollTicker := time.NewTicker(logPollInterval)
+	defer pollTicker.Stop()
+	reportTicker := time.NewTicker(lt.config.ReportInterval)
+	defer reportTicker.Stop()
+
+	lt.poll()
+
+	for {
+		select {
+		case <-lt.stop:
+			lt.close()
+			lt.report()
+			return
+		case <-pollTicker.C:
+			lt.poll()
+		case <-reportTicker.C:
+			lt.report()
+		}
+	}
+}
+
+// Stop tailing log files
+func (lt *LogTailer) Stop() {
+	lt.stop <- struct{}{}
+}
+
+// poll reads new lines from every log file and saves offsets
+func (lt *LogTailer) poll() {
+	seen := make(map[string]bool)
+
+	for _, app := range lt.config.AppPaths {
+		files, _ := filepath.Glob(filepath.Join(app, laravelLogPattern))
+		for _, file := range files {
+			seen[file] = true
+
+			if err := lt.tail(app, file); err != nil {
+				log.Trace().Err(err).Str("file", file).Msg("Failed to tail log file")
+			}
+		}
+	}
+
+	// finish and forget files that were rotated away or deleted
+	for file, state := range lt.files {
+		if !seen[file] {
+			lt.drain(file, state)
+			delete(lt.files, file)
+		}
+	}
+
+	lt.started = true
+
+	if err := lt.saveState(); err != nil {
+		log.Trace().Err(err).Msg("Failed to save log offsets")
+	}
+}
+
+func (lt *LogTailer) tail(app, file string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	inode := fileInode(info)
+	state, ok := lt.files[file]
+	switch {
+	case !ok && !lt.started:
+		// don't replay history on the very first run
+		state = &tailedFile{Offset: info.Size(), Inode: inode}
+	case !ok:
+		// created after we started, e.g. a new daily file
+		state = &tailedFile{Inode: inode}
+	case state.Inode != inode:
+		// replaced, e.g. renamed to laravel.log.1 by logrotate
+		lt.drain(file, state)
+		state = &tailedFile{Inode: inode}
+	case info.Size() < state.Offset:
+		// truncated in place
+		state.Offset = 0
+	}
+	state.app = app
+	lt.files[file] = state
+
+	if state.file == nil {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		state.file = f
+	}
+
+	return lt.read(file, state)
+}
+
+// read parses complete lines written since the previous read. The last entry is recorded once a poll
+// brings no new lines, until then continuation lines are appended to it.
+func (lt *LogTailer) read(file string, state *tailedFile) error {
+	if _, err := state.file.Seek(state.Offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	offset := state.Offset
+	reader := bufio.NewReader(state.file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// leave incomplete lines for the next poll
+			break
+		}
+		state.Offset += int64(len(line))
+		line = strings.TrimRight(line, "\r\n")
+
+		if parsed := parseMonologLine(line); parsed != nil {
+			lt.record(state.app, state.entry)
+			state.entry = parsed
+			state.entry.File = filepath.Base(file)
+
+			continue
+		}
+
+		if state.entry != nil && len(state.entry.Message) < logMessageLimit {
+			state.entry.Message = truncate(state.entry.Message+"\n"+line, logMessageLimit)
+		}
+	}
+
+	if state.Offset == offset {
+		lt.record(state.app, state.entry)
+		state.entry = nil
+	}
+
+	return nil
+}
+
+// drain reads what's left of a file which is no longer tailed, records its last entry and closes it
+func (lt *LogTailer) drain(file string, state *tailedFile) {
+	if state.file != nil {
+		if err := lt.read(file, state); err != nil {
+			log.Trace().Err(err).Str("file", file).Msg("Failed to read rotated log file")
+		}
+		state.file.Close()
+		state.file = nil
+	}
+
+	lt.record(state.app, state.entry)
+	state.entry = nil
+}
+
+// close records pending entries and closes tailed files
+func (lt *LogTailer) close() {
+	for _, state := range lt.files {
+		lt.record(state.app, state.entry)
+		state.entry = nil
+
+		if state.file != nil {
+			state.file.Close()
+			state.file = nil
+		}
+	}
+}
+
+// record counts an entry and samples it when severe enough
+func (lt *LogTailer) record(app string, entry *metrics.LogEntry) {
+	if entry == nil {
+		return
+	}
+
+	report, ok := lt.reports[app]
+	if !ok {
+		report = &metrics.LogReport{
+			AppPath:   app,
+			StartedAt: time.Now().Format(time.RFC3339),
+			Counts:    make(map[string]uint64),
+		}
+		lt.reports[app] = report
+	}
+
+	report.Counts[entry.Level]++
+	if forwardedLogLevels[entry.Level] && len(report.Entries) < lt.config.SampleLimit {
+		report.Entries = append(report.Entries, *entry)
+	}
+}
+
+// report forwards per-application summaries as app metrics
+func (lt *LogTailer) report() {
+	apps := make([]string, 0, len(lt.reports))
+	for app := range lt.reports {
+		apps = append(apps, app)
+	}
+	sort.Strings(apps)
+
+	for _, app := range apps {
+		report := lt.reports[app]
+		report.EndedAt = time.Now().Format(time.RFC3339)
+
+		payload, err := json.Marshal(map[string]interface{}{
+			"env": lt.config.Environment,
+			"log": report,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to encode log report")
+			continue
+		}
+
+		lt.bucket.Add(metrics.NewAppMetric(string(payload)))
+	}
+
+	lt.reports = make(map[string]*metrics.LogReport)
+}
+
+func (lt *LogTailer) loadState() error {
+	if len(lt.config.StateFile) == 0 {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(lt.config.StateFile)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(contents, &lt.files); err != nil {
+		return errors.Wrap(err, "Invalid log state file")
+	}
+
+	// offsets exist, so files created while the agent was down are read from the start
+	lt.started = true
+
+	return nil
+}
+
+// saveState atomically writes offsets to the state file
+func (lt *LogTailer) saveState() error {
+	if len(lt.config.StateFile) == 0 {
+		return nil
+	}
+
+	contents, err := json.Marshal(lt.files)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(contents, lt.saved) {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(lt.config.StateFile), 0750); err != nil {
+		return err
+	}
+
+	tmp := lt.config.StateFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, contents, 0640); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, lt.config.StateFile); err != nil {
+		return err
+	}
+	lt.saved = contents
+
+	return nil
+}
+
+// parseMonologLine parses the first line of a Monolog entry, nil for continuation lines
+func parseMonologLine(line string) *metrics.LogEntry {
+	matches := monologLine.FindStringSubmatch(line)
+	if matches == nil {
+		return nil
+	}
+
+	return &metrics.LogEntry{
+		CreatedAt: matches[1],
+		Channel:   matches[2],
+		Level:     matches[3],
+		Message:   truncate(matches[4], logMessageLimit),
+	}
+}
+
+func fileInode(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+
+	return 0
+}
@@ -0,0 +1,120 @@
+package collectors
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/monitoring/buckets"
+)
+
+func TestParseMonologLine(t *testing.T) {
+	entry := parseMonologLine(`[2021-01-05 12:34:56] production.ERROR: Division by zero {"exception":"[object] (DivisionByZeroError(code: 0))"}`)
+	assert.Equal(t, "2021-01-05 12:34:56", entry.CreatedAt)
+	assert.Equal(t, "production", entry.Channel)
+	assert.Equal(t, "ERROR", entry.Level)
+	assert.Equal(t, `Division by zero {"exception":"[object] (DivisionByZeroError(code: 0))"}`, entry.Message)
+
+	entry = parseMonologLine(`[2021-01-05T12:34:56.123456+00:00] local.INFO: done`)
+	assert.Equal(t, "INFO", entry.Level)
+
+	assert.Nil(t, parseMonologLine(`#0 /var/www/app/Http/Kernel.php(12): handle()`))
+}
+
+func TestLogTailer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "laravel")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logs := filepath.Join(dir, "storage", "logs")
+	assert.NoError(t, os.MkdirAll(logs, 0755))
+
+	daily := filepath.Join(logs, "laravel-2021-01-05.log")
+	appendLog := func(path, content string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		assert.NoError(t, err)
+		_, err = f.WriteString(content)
+		assert.NoError(t, err)
+		assert.NoError(t, f.Close())
+	}
+
+	appendLog(daily, "[2021-01-05 10:00:00] production.ERROR: old error\n")
+
+	cfg := LogTailerConfig{
+		AppPaths:       []string{dir},
+		StateFile:      filepath.Join(dir, "state", "offsets.json"),
+		SampleLimit:    2,
+		Environment:    "production",
+		ReportInterval: time.Minute,
+	}
+	bucket := buckets.NewAppMetricBucket()
+	tailer := NewLogTailer(cfg, bucket)
+
+	// existing history is skipped on the first run
+	tailer.poll()
+	assert.Empty(t, tailer.reports)
+
+	appendLog(daily, "[2021-01-05 10:01:00] production.INFO: started\n"+
+		"[2021-01-05 10:01:01] production.ERROR: first failure\n[stacktrace]\n#0 {main}\n"+
+		"[2021-01-05 10:01:02] production.CRITICAL: second failure\n[stacktrace]\n")
+	tailer.poll()
+
+	report := tailer.reports[dir]
+	assert.Equal(t, map[string]uint64{"INFO": 1, "ERROR": 1}, report.Counts)
+	assert.Len(t, report.Entries, 1)
+	assert.Equal(t, "first failure\n[stacktrace]\n#0 {main}", report.Entries[0].Message)
+	assert.Equal(t, "laravel-2021-01-05.log", report.Entries[0].File)
+
+	// the last entry continues with the next poll and is recorded once no more lines follow
+	appendLog(daily, "#0 {main}\n[2021-01-05 10:01:03] production.ERROR: partial")
+	tailer.poll()
+	assert.Equal(t, map[string]uint64{"INFO": 1, "ERROR": 1}, report.Counts)
+	tailer.poll()
+	assert.Equal(t, map[string]uint64{"INFO": 1, "ERROR": 1, "CRITICAL": 1}, report.Counts)
+	assert.Len(t, report.Entries, 2)
+	assert.Equal(t, "second failure\n[stacktrace]\n#0 {main}", report.Entries[1].Message)
+
+	tailer.report()
+	assert.Equal(t, 1, bucket.Count())
+	payload := map[string]json.RawMessage{}
+	assert.NoError(t, json.Unmarshal([]byte((*bucket.All())[0].String()), &payload))
+	assert.Contains(t, payload, "log")
+	assert.Empty(t, tailer.reports)
+
+	// the incomplete line is finished, then a new daily file shows up
+	appendLog(daily, " line\n")
+	appendLog(filepath.Join(logs, "laravel-2021-01-06.log"), "[2021-01-06 00:00:01] production.WARNING: new day\n")
+	tailer.poll()
+	tailer.poll()
+	assert.Equal(t, map[string]uint64{"ERROR": 1, "WARNING": 1}, tailer.reports[dir].Counts)
+	tailer.report()
+
+	// truncation restarts from the beginning
+	assert.NoError(t, ioutil.WriteFile(daily, []byte("[2021-01-05 11:00:00] production.DEBUG: after truncate\n"), 0644))
+	tailer.poll()
+	tailer.poll()
+	assert.Equal(t, map[string]uint64{"DEBUG": 1}, tailer.reports[dir].Counts)
+	tailer.report()
+
+	// lines written right before rotation are read from the renamed file
+	appendLog(daily, "[2021-01-05 11:00:01] production.ERROR: before rotation\n")
+	assert.NoError(t, os.Rename(daily, daily+".1"))
+	appendLog(daily, "[2021-01-05 11:00:02] production.INFO: after rotation\n")
+	tailer.poll()
+	tailer.poll()
+	assert.Equal(t, map[string]uint64{"ERROR": 1, "INFO": 1}, tailer.reports[dir].Counts)
+
+	// offsets survive a restart
+	appendLog(daily, "[2021-01-05 11:00:03] production.NOTICE: while stopped\n")
+	restarted := NewLogTailer(cfg, bucket)
+	restarted.poll()
+	restarted.poll()
+	assert.Equal(t, map[string]uint64{"NOTICE": 1}, restarted.reports[dir].Counts)
+	restarted.close()
+	tailer.close()
+}
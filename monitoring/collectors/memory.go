@@ -0,0 +1,71 @@
+package collectors
+
+import (
+	"os"
+	"time"
+
+	"github.com/shirou/gopsutil/mem"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// vmStat holds cumulative counters read from /proc/vmstat
+type vmStat struct {
+	swapIn      uint64
+	swapOut     uint64
+	oomKill     uint64
+	collectedAt time.Time
+}
+
+func readVMStat(path string) (*vmStat, error) {
+	values, err := readKeyValueFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &vmStat{
+		swapIn:      values["pswpin"],
+		swapOut:     values["pswpout"],
+		oomKill:     values["oom_kill"],
+		collectedAt: time.Now(),
+	}, nil
+}
+
+// memoryBreakdown builds the detailed memory metric. Swap rates and OOM kills are
+// calculated against the previous collection, so the first one reports zeroes.
+func memoryBreakdown(m *mem.VirtualMemoryStat, current, previous *vmStat) *metrics.ServerMemory {
+	memory := &metrics.ServerMemory{
+		Available: m.Available,
+		Buffers:   m.Buffers,
+		Cached:    m.Cached,
+		Slab:      m.Slab,
+		SwapTotal: m.SwapTotal,
+	}
+
+	if m.SwapTotal > m.SwapFree {
+		memory.SwapUsed = m.SwapTotal - m.SwapFree
+	}
+
+	if current == nil || previous == nil {
+		return memory
+	}
+
+	seconds := current.collectedAt.Sub(previous.collectedAt).Seconds()
+	pageSize := float64(os.Getpagesize())
+	if seconds > 0 {
+		memory.SwapInRate = float64(counterDelta(current.swapIn, previous.swapIn)) * pageSize / seconds
+		memory.SwapOutRate = float64(counterDelta(current.swapOut, previous.swapOut)) * pageSize / seconds
+	}
+	memory.OOMKills = counterDelta(current.oomKill, previous.oomKill)
+
+	return memory
+}
+
+// counterDelta returns the growth of a cumulative counter, treating resets as zero
+func counterDelta(current, previous uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+
+	return current - previous
+}
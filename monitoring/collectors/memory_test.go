@@ -0,0 +1,57 @@
+package collectors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/mem"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadVMStat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vmstat")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "vmstat")
+	content := "nr_free_pages 123\npswpin 10\npswpout 20\noom_kill 3\n"
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+
+	stat, err := readVMStat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), stat.swapIn)
+	assert.Equal(t, uint64(20), stat.swapOut)
+	assert.Equal(t, uint64(3), stat.oomKill)
+}
+
+func TestMemoryBreakdown(t *testing.T) {
+	m := &mem.VirtualMemoryStat{
+		Available: 100,
+		Buffers:   10,
+		Cached:    20,
+		Slab:      5,
+		SwapTotal: 1000,
+		SwapFree:  400,
+	}
+	now := time.Now()
+	previous := &vmStat{swapIn: 0, swapOut: 10, oomKill: 1, collectedAt: now.Add(-10 * time.Second)}
+	current := &vmStat{swapIn: 10, swapOut: 10, oomKill: 3, collectedAt: now}
+
+	first := memoryBreakdown(m, current, nil)
+	assert.Equal(t, uint64(600), first.SwapUsed)
+	assert.Equal(t, uint64(0), first.OOMKills)
+	assert.Equal(t, float64(0), first.SwapInRate)
+
+	memory := memoryBreakdown(m, current, previous)
+	assert.Equal(t, uint64(100), memory.Available)
+	assert.Equal(t, uint64(2), memory.OOMKills)
+	assert.Equal(t, float64(os.Getpagesize()), memory.SwapInRate)
+	assert.Equal(t, float64(0), memory.SwapOutRate)
+
+	// counter reset after a reboot must not underflow
+	reset := memoryBreakdown(m, &vmStat{collectedAt: now}, previous)
+	assert.Equal(t, uint64(0), reset.OOMKills)
+}
@@ -0,0 +1,206 @@
+package collectors
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+
+	// registers the "mysql" database/sql driver
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const mysqlQueryTimeout = 5 * time.Second
+
+// MySQLCollector reports MySQL/MariaDB server health from `SHOW GLOBAL STATUS`
+type MySQLCollector struct {
+	db          *sql.DB
+	previous    map[string]uint64
+	collectedAt time.Time
+}
+
+// NewMySQLCollector creates a new instance of `MySQLCollector`.
+// The connection is established lazily, on the first collection.
+func NewMySQLCollector(dsn string) (*MySQLCollector, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to configure MySQL connection")
+	}
+
+	return newMySQLCollector(db), nil
+}
+
+func newMySQLCollector(db *sql.DB) *MySQLCollector {
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	return &MySQLCollector{db: db}
+}
+
+// Collect queries server status
+func (c *MySQLCollector) Collect() (*metrics.MySQL, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mysqlQueryTimeout)
+	defer cancel()
+
+	status, err := c.keyValues(ctx, "SHOW GLOBAL STATUS")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch MySQL status")
+	}
+
+	variables, err := c.keyValues(ctx, "SHOW GLOBAL VARIABLES LIKE 'max_connections'")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch MySQL variables")
+	}
+
+	mysql := &metrics.MySQL{
+		Uptime:              status["Uptime"],
+		MaxConnections:      variables["max_connections"],
+		MaxUsedConnections:  status["Max_used_connections"],
+		ThreadsConnected:    status["Threads_connected"],
+		ThreadsRunning:      status["Threads_running"],
+		RowLockCurrentWaits: status["Innodb_row_lock_current_waits"],
+	}
+
+	if err := c.db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&mysql.Version); err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch MySQL version")
+	}
+
+	// requires the REPLICATION CLIENT privilege, which monitoring users often lack
+	mysql.Replica, mysql.ReplicationLag, err = c.replication(ctx)
+	if err != nil {
+		log.Trace().Err(err).Msg("Failed to fetch MySQL replication status")
+	}
+
+	now := time.Now()
+	c.applyDeltas(mysql, status, now.Sub(c.collectedAt).Seconds())
+	c.previous = status
+	c.collectedAt = now
+
+	return mysql, nil
+}
+
+// applyDeltas fills counters that are relative to the previous collection.
+// The buffer pool hit rate falls back to lifetime totals on the first collection.
+func (c *MySQLCollector) applyDeltas(mysql *metrics.MySQL, status map[string]uint64, seconds float64) {
+	readRequests := status["Innodb_buffer_pool_read_requests"]
+	diskReads := status["Innodb_buffer_pool_reads"]
+
+	if c.previous != nil {
+		delta := func(key string) uint64 {
+			return counterDelta(status[key], c.previous[key])
+		}
+
+		mysql.AbortedConnects = delta("Aborted_connects")
+		mysql.SlowQueries = delta("Slow_queries")
+		mysql.RowLockWaits = delta("Innodb_row_lock_waits")
+		if seconds > 0 {
+			mysql.QueriesPerSecond = float64(delta("Questions")) / seconds
+		}
+
+		readRequests = delta("Innodb_buffer_pool_read_requests")
+		diskReads = delta("Innodb_buffer_pool_reads")
+	}
+
+	mysql.BufferPoolHitRate = 100
+	if readRequests > 0 && diskReads <= readRequests {
+		mysql.BufferPoolHitRate = (1 - float64(diskReads)/float64(readRequests)) * 100
+	}
+}
+
+// keyValues runs a query returning name/value rows, skipping non-numeric values
+func (c *MySQLCollector) keyValues(ctx context.Context, query string) (map[string]uint64, error) {
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]uint64)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+
+		if number, err := strconv.ParseUint(value, 10, 64); err == nil {
+			values[name] = number
+		}
+	}
+
+	return values, rows.Err()
+}
+
+// replication reads replica lag. `SHOW REPLICA STATUS` is tried first (MySQL 8.0.22+),
+// falling back to `SHOW SLAVE STATUS` for older MySQL and MariaDB versions.
+func (c *MySQLCollector) replication(ctx context.Context) (bool, *int64, error) {
+	row, err := c.firstRow(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		row, err = c.firstRow(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return false, nil, err
+		}
+	}
+
+	if row == nil {
+		return false, nil, nil
+	}
+
+	for _, column := range []string{"Seconds_Behind_Source", "Seconds_Behind_Master"} {
+		value, ok := row[column]
+		if !ok {
+			continue
+		}
+
+		lag, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			// NULL when the SQL thread isn't running
+			return true, nil, nil
+		}
+
+		return true, &lag, nil
+	}
+
+	return true, nil, nil
+}
+
+// firstRow returns the first row of a query keyed by column name, or nil if there are no rows
+func (c *MySQLCollector) firstRow(ctx context.Context, query string) (map[string]string, error) {
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	values := make([]sql.NullString, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]string, len(columns))
+	for i, column := range columns {
+		if values[i].Valid {
+			row[strings.TrimSpace(column)] = values[i].String
+		}
+	}
+
+	return row, nil
+}
@@ -0,0 +1,133 @@
+package collectors
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// mysqlStandIn is a database/sql driver answering the queries issued by `MySQLCollector`
+type mysqlStandIn struct {
+	results map[string]*standInRows
+}
+
+type standInConn struct {
+	driver *mysqlStandIn
+}
+
+type standInStmt struct {
+	conn  *standInConn
+	query string
+}
+
+type standInRows struct {
+	columns []string
+	values  [][]driver.Value
+	cursor  int
+}
+
+func (d *mysqlStandIn) Open(name string) (driver.Conn, error) { return &standInConn{d}, nil }
+
+// the stand-in is its own connector, so it's opened without registering a driver name
+func (d *mysqlStandIn) Connect(ctx context.Context) (driver.Conn, error) { return d.Open("") }
+func (d *mysqlStandIn) Driver() driver.Driver                            { return d }
+
+func (c *standInConn) Prepare(query string) (driver.Stmt, error) { return &standInStmt{c, query}, nil }
+func (c *standInConn) Close() error                              { return nil }
+func (c *standInConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+func (s *standInStmt) Close() error  { return nil }
+func (s *standInStmt) NumInput() int { return -1 }
+func (s *standInStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s *standInStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows, ok := s.conn.driver.results[s.query]
+	if !ok {
+		return nil, errors.Errorf("unexpected query %q", s.query)
+	}
+
+	return &standInRows{columns: rows.columns, values: rows.values}, nil
+}
+
+func (r *standInRows) Columns() []string { return r.columns }
+func (r *standInRows) Close() error      { return nil }
+func (r *standInRows) Next(dest []driver.Value) error {
+	if r.cursor >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.cursor])
+	r.cursor++
+
+	return nil
+}
+
+func statusRows(values map[string]string) *standInRows {
+	rows := &standInRows{columns: []string{"Variable_name", "Value"}}
+	for name, value := range values {
+		rows.values = append(rows.values, []driver.Value{name, value})
+	}
+
+	return rows
+}
+
+func TestMySQLCollector(t *testing.T) {
+	standIn := &mysqlStandIn{results: map[string]*standInRows{
+		"SELECT VERSION()": {columns: []string{"VERSION()"}, values: [][]driver.Value{{"8.0.23"}}},
+		"SHOW GLOBAL VARIABLES LIKE 'max_connections'": statusRows(map[string]string{"max_connections": "151"}),
+		"SHOW REPLICA STATUS": {
+			columns: []string{"Replica_IO_State", "Seconds_Behind_Source"},
+			values:  [][]driver.Value{{"Waiting for source", "7"}},
+		},
+	}}
+	collector := newMySQLCollector(sql.OpenDB(standIn))
+
+	standIn.results["SHOW GLOBAL STATUS"] = statusRows(map[string]string{
+		"Uptime":                           "3600",
+		"Threads_connected":                "12",
+		"Threads_running":                  "3",
+		"Questions":                        "1000",
+		"Slow_queries":                     "5",
+		"Innodb_buffer_pool_read_requests": "1000",
+		"Innodb_buffer_pool_reads":         "100",
+		"Innodb_row_lock_waits":            "2",
+		"Compression":                      "OFF",
+	})
+
+	mysql, err := collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, "8.0.23", mysql.Version)
+	assert.Equal(t, uint64(151), mysql.MaxConnections)
+	assert.Equal(t, uint64(12), mysql.ThreadsConnected)
+	assert.Equal(t, uint64(0), mysql.SlowQueries)
+	assert.InDelta(t, 90, mysql.BufferPoolHitRate, 0.001)
+	assert.True(t, mysql.Replica)
+	assert.Equal(t, int64(7), *mysql.ReplicationLag)
+
+	standIn.results["SHOW GLOBAL STATUS"] = statusRows(map[string]string{
+		"Questions":                        "1500",
+		"Slow_queries":                     "8",
+		"Innodb_buffer_pool_read_requests": "2000",
+		"Innodb_buffer_pool_reads":         "100",
+		"Innodb_row_lock_waits":            "6",
+	})
+	delete(standIn.results, "SHOW REPLICA STATUS")
+	standIn.results["SHOW SLAVE STATUS"] = &standInRows{
+		columns: []string{"Slave_IO_State", "Seconds_Behind_Master"},
+		values:  [][]driver.Value{{"", nil}},
+	}
+
+	mysql, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), mysql.SlowQueries)
+	assert.Equal(t, uint64(4), mysql.RowLockWaits)
+	assert.True(t, mysql.QueriesPerSecond > 0)
+	assert.Equal(t, float64(100), mysql.BufferPoolHitRate)
+	assert.True(t, mysql.Replica)
+	assert.Nil(t, mysql.ReplicationLag)
+}
@@ -0,0 +1,469 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const (
+	packageManagerApt = "apt"
+	packageManagerDnf = "dnf"
+	packageManagerYum = "yum"
+
+	// results are recomputed when package databases change, or at least this often
+	packageUpdateMaxAge = time.Hour
+
+	packageCommandTimeout = time.Minute
+
+	// yum keeps its cache per architecture and release, both expanded by yum itself
+	yumCacheDir = "var/cache/yum/$basearch/$releasever"
+)
+
+var (
+	// printed by dnf/yum when the cache can't be found or read, e.g. when not running as root
+	packageCacheUnavailable = regexp.MustCompile(`(?i)cache-only enabled but no cache|permission denied`)
+
+	updateNotifierPending  = regexp.MustCompile(`(?m)^(\d+) (?:packages|updates) can be (?:updated|applied immediately)`)
+	updateNotifierSecurity = regexp.MustCompile(`(?m)^(\d+) (?:of these updates are (?:standard )?security updates|updates are security updates)`)
+)
+
+// PackageUpdateCollector counts pending package and security updates from local package manager
+// metadata, without refreshing it over the network
+type PackageUpdateCollector struct {
+	root    string
+	command func(name string, args ...string) ([]byte, int, error)
+
+	fingerprint string
+	cached      *metrics.PackageUpdates
+	checkedAt   time.Time
+}
+
+// NewPackageUpdateCollector creates a new instance of `PackageUpdateCollector`
+func NewPackageUpdateCollector() *PackageUpdateCollector {
+	return &PackageUpdateCollector{
+		root:    "/",
+		command: runCommand,
+	}
+}
+
+// Collect returns pending updates, nil when no supported package manager is found
+func (c *PackageUpdateCollector) Collect() (*metrics.PackageUpdates, error) {
+	manager := c.manager()
+	if len(manager) == 0 {
+		return nil, nil
+	}
+
+	fingerprint := c.fingerprintFor(manager)
+	if c.cached != nil && fingerprint == c.fingerprint && time.Since(c.checkedAt) < packageUpdateMaxAge {
+		return c.cached, nil
+	}
+
+	var updates *metrics.PackageUpdates
+	var err error
+	if manager == packageManagerApt {
+		updates, err = c.apt()
+	} else {
+		updates, err = c.dnf(manager)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.checkedAt = time.Now()
+	updates.CheckedAt = metrics.FormatTime(c.checkedAt)
+	c.fingerprint = fingerprint
+	c.cached = updates
+
+	return updates, nil
+}
+
+func (c *PackageUpdateCollector) path(parts ...string) string {
+	return filepath.Join(append([]string{c.root}, parts...)...)
+}
+
+func (c *PackageUpdateCollector) manager() string {
+	if _, err := os.Stat(c.path("var/lib/dpkg/status")); err == nil {
+		return packageManagerApt
+	}
+
+	for _, manager := range []string{packageManagerDnf, packageManagerYum} {
+		if _, err := os.Stat(c.path("var/cache", manager)); err == nil {
+			return manager
+		}
+	}
+
+	return ""
+}
+
+// fingerprintFor combines modification times of the files package managers touch on install and refresh
+func (c *PackageUpdateCollector) fingerprintFor(manager string) string {
+	var paths []string
+	if manager == packageManagerApt {
+		paths = []string{
+			c.path("var/lib/dpkg/status"),
+			c.path("var/lib/apt/lists"),
+			c.path("var/lib/update-notifier/updates-available"),
+			c.path("var/run/reboot-required.pkgs"),
+		}
+	} else {
+		paths = []string{c.path("var/lib/rpm")}
+		repodata, _ := filepath.Glob(c.path("var/cache", manager, "*", "repodata"))
+		paths = append(paths, repodata...)
+	}
+
+	var fingerprint strings.Builder
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			fingerprint.WriteString(path + "@" + strconv.FormatInt(info.ModTime().UnixNano(), 10) + ";")
+		}
+	}
+
+	return fingerprint.String()
+}
+
+func (c *PackageUpdateCollector) apt() (*metrics.PackageUpdates, error) {
+	updates := &metrics.PackageUpdates{Manager: packageManagerApt}
+
+	// update-notifier keeps a summary fresh on Ubuntu, otherwise compare installed packages with apt lists
+	contents, err := ioutil.ReadFile(c.path("var/lib/update-notifier/updates-available"))
+	if err == nil {
+		updates.Pending, updates.Security = parseUpdatesAvailable(string(contents))
+	} else {
+		updates.Pending, updates.Security, err = c.aptLists()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	updates.RestartRequired = readLines(c.path("var/run/reboot-required.pkgs"))
+
+	return updates, nil
+}
+
+// parseUpdatesAvailable parses update-notifier's updates-available file
+func parseUpdatesAvailable(contents string) (pending, security int) {
+	if m := updateNotifierPending.FindStringSubmatch(contents); m != nil {
+		pending, _ = strconv.Atoi(m[1])
+	}
+	if m := updateNotifierSecurity.FindStringSubmatch(contents); m != nil {
+		security, _ = strconv.Atoi(m[1])
+	}
+
+	return pending, security
+}
+
+// aptLists counts installed packages with a newer version in downloaded apt lists.
+// Pinning and held packages are not taken into account.
+func (c *PackageUpdateCollector) aptLists() (pending, security int, err error) {
+	installed := make(map[string]string)
+	err = readDebianControl(c.path("var/lib/dpkg/status"), func(fields map[string]string) {
+		if strings.HasSuffix(fields["Status"], " installed") {
+			installed[fields["Package"]+":"+fields["Architecture"]] = fields["Version"]
+		}
+	})
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "Failed to read dpkg status")
+	}
+
+	lists, _ := filepath.Glob(c.path("var/lib/apt/lists", "*_Packages"))
+	upgradable := make(map[string]bool)
+	for _, list := range lists {
+		isSecurity := strings.Contains(filepath.Base(list), "security")
+		err := readDebianControl(list, func(fields map[string]string) {
+			key := fields["Package"] + ":" + fields["Architecture"]
+			version, ok := installed[key]
+			if !ok || compareDebianVersions(fields["Version"], version) <= 0 {
+				return
+			}
+
+			upgradable[key] = upgradable[key] || isSecurity
+		})
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "Failed to read %s", list)
+		}
+	}
+
+	for _, isSecurity := range upgradable {
+		pending++
+		if isSecurity {
+			security++
+		}
+	}
+
+	return pending, security, nil
+}
+
+// readDebianControl calls fn for every paragraph of a Debian control file, keeping single line fields only
+func readDebianControl(path string, fn func(fields map[string]string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fields := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) == 0 {
+			if len(fields) > 0 {
+				fn(fields)
+				fields = make(map[string]string)
+			}
+
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) == 2 {
+			fields[parts[0]] = strings.TrimSpace(parts[1])
+		}
+	}
+	if len(fields) > 0 {
+		fn(fields)
+	}
+
+	return scanner.Err()
+}
+
+// compareDebianVersions compares [epoch:]upstream[-revision] versions as dpkg does
+func compareDebianVersions(a, b string) int {
+	epochA, upstreamA, revisionA := splitDebianVersion(a)
+	epochB, upstreamB, revisionB := splitDebianVersion(b)
+
+	if epochA != epochB {
+		if epochA < epochB {
+			return -1
+		}
+		return 1
+	}
+
+	if cmp := compareDebianPart(upstreamA, upstreamB); cmp != 0 {
+		return cmp
+	}
+
+	return compareDebianPart(revisionA, revisionB)
+}
+
+func splitDebianVersion(version string) (epoch int, upstream, revision string) {
+	if i := strings.Index(version, ":"); i >= 0 {
+		epoch, _ = strconv.Atoi(version[:i])
+		version = version[i+1:]
+	}
+
+	if i := strings.LastIndex(version, "-"); i >= 0 {
+		return epoch, version[:i], version[i+1:]
+	}
+
+	return epoch, version, ""
+}
+
+func compareDebianPart(a, b string) int {
+	for len(a) > 0 || len(b) > 0 {
+		// non-digit prefixes compare by dpkg's lexical order
+		for len(a) > 0 && !isDigit(a[0]) || len(b) > 0 && !isDigit(b[0]) {
+			orderA, orderB := debianOrder(a), debianOrder(b)
+			if orderA != orderB {
+				if orderA < orderB {
+					return -1
+				}
+				return 1
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		var numberA, numberB string
+		numberA, a = leadingDigits(a)
+		numberB, b = leadingDigits(b)
+
+		numberA = strings.TrimLeft(numberA, "0")
+		numberB = strings.TrimLeft(numberB, "0")
+		if len(numberA) != len(numberB) {
+			if len(numberA) < len(numberB) {
+				return -1
+			}
+			return 1
+		}
+		if numberA != numberB {
+			if numberA < numberB {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// debianOrder returns the sort weight of the first character: ~ sorts before the end of the string,
+// letters before other characters
+func debianOrder(s string) int {
+	if len(s) == 0 || isDigit(s[0]) {
+		return 0
+	}
+
+	c := s[0]
+	switch {
+	case c == '~':
+		return -1
+	case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+func leadingDigits(s string) (digits, rest string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (c *PackageUpdateCollector) dnf(manager string) (*metrics.PackageUpdates, error) {
+	updates := &metrics.PackageUpdates{Manager: manager}
+
+	// -C only uses cached metadata; check-update exits with 100 when updates are available.
+	// Non-root users get a per-user cache under /var/tmp by default, so point at the system one.
+	cacheDir := "--setopt=cachedir=" + c.cacheDir(manager)
+	out, code, err := c.command(manager, "-C", "-q", cacheDir, "check-update")
+	if err != nil || code != 0 && code != 100 {
+		if packageCacheUnavailable.Match(out) {
+			return nil, errors.Errorf("%s check-update failed: the agent user can't read the system package cache in %s: %s",
+				manager, c.cacheDir(manager), strings.TrimSpace(string(out)))
+		}
+
+		return nil, errors.Errorf("%s check-update failed: %v %s", manager, err, strings.TrimSpace(string(out)))
+	}
+	updates.Pending = countPackageLines(out)
+
+	out, code, err = c.command(manager, "-C", "-q", cacheDir, "updateinfo", "list", "--security")
+	if err == nil && code == 0 {
+		updates.Security = countSecurityAdvisories(out)
+	}
+
+	// needs-restarting comes with dnf-plugins-core / yum-utils and exits with 1 when a reboot is needed
+	out, code, err = c.command("needs-restarting", "-r")
+	if err == nil && code == 1 {
+		updates.RestartRequired = parseNeedsRestarting(out)
+	}
+
+	return updates, nil
+}
+
+func (c *PackageUpdateCollector) cacheDir(manager string) string {
+	if manager == packageManagerYum {
+		return c.path(yumCacheDir)
+	}
+
+	return c.path("var/cache", manager)
+}
+
+// countPackageLines counts "name.arch version repo" lines, stopping at the obsoleting packages section
+func countPackageLines(out []byte) int {
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "Obsoleting") {
+			break
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 3 && strings.Contains(fields[0], ".") && !strings.HasPrefix(line, " ") {
+			count++
+		}
+	}
+
+	return count
+}
+
+// countSecurityAdvisories counts distinct packages in "advisory severity/Sec. package" lines
+func countSecurityAdvisories(out []byte) int {
+	packages := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 {
+			packages[fields[2]] = true
+		}
+	}
+
+	return len(packages)
+}
+
+func parseNeedsRestarting(out []byte) []string {
+	var packages []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "* ") {
+			packages = append(packages, strings.TrimPrefix(line, "* "))
+		}
+	}
+
+	return packages
+}
+
+// readLines returns unique non-empty lines of a file
+func readLines(path string) []string {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var lines []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || seen[line] {
+			continue
+		}
+		seen[line] = true
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// runCommand runs a package manager command returning its output and exit code
+func runCommand(name string, args ...string) ([]byte, int, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), packageCommandTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, args...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return out, exitErr.ExitCode(), nil
+	}
+
+	return out, 0, err
+}
@@ -0,0 +1,139 @@
+package collectors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareDebianVersions(t *testing.T) {
+	assert.Equal(t, 0, compareDebianVersions("1.2.3-1", "1.2.3-1"))
+	assert.Equal(t, 1, compareDebianVersions("1.2.10", "1.2.9"))
+	assert.Equal(t, 1, compareDebianVersions("1:0.9", "2.0"))
+	assert.Equal(t, -1, compareDebianVersions("1.0~rc1", "1.0"))
+	assert.Equal(t, 1, compareDebianVersions("1.0a", "1.0"))
+	assert.Equal(t, -1, compareDebianVersions("1.0a", "1.0+"))
+	assert.Equal(t, 1, compareDebianVersions("2.31-0ubuntu9.9", "2.31-0ubuntu9.2"))
+}
+
+func TestPackageUpdateCollectorApt(t *testing.T) {
+	root, err := ioutil.TempDir("", "packages")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	write := func(path, contents string) {
+		path = filepath.Join(root, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+
+	write("var/lib/dpkg/status", `Package: libc6
+Status: install ok installed
+Architecture: amd64
+Version: 2.31-0ubuntu9.2
+Description: GNU C Library
+ multi line description
+
+Package: curl
+Status: install ok installed
+Architecture: amd64
+Version: 7.68.0-1ubuntu2.5
+
+Package: removed
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0
+`)
+	write("var/lib/apt/lists/archive.ubuntu.com_ubuntu_dists_focal-updates_main_binary-amd64_Packages", `Package: curl
+Architecture: amd64
+Version: 7.68.0-1ubuntu2.7
+
+Package: libc6
+Architecture: amd64
+Version: 2.31-0ubuntu9.2
+
+Package: removed
+Architecture: amd64
+Version: 2.0
+`)
+	write("var/lib/apt/lists/security.ubuntu.com_ubuntu_dists_focal-security_main_binary-amd64_Packages", `Package: libc6
+Architecture: amd64
+Version: 2.31-0ubuntu9.9
+`)
+	write("var/run/reboot-required.pkgs", "libc6\nlinux-base\nlibc6\n")
+
+	collector := NewPackageUpdateCollector()
+	collector.root = root
+
+	updates, err := collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, "apt", updates.Manager)
+	assert.Equal(t, 2, updates.Pending)
+	assert.Equal(t, 1, updates.Security)
+	assert.Equal(t, []string{"libc6", "linux-base"}, updates.RestartRequired)
+
+	// update-notifier's summary is preferred once present
+	write("var/lib/update-notifier/updates-available", "\n12 updates can be applied immediately.\n5 of these updates are standard security updates.\nTo see these additional updates run: apt list --upgradable\n")
+
+	updates, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, 12, updates.Pending)
+	assert.Equal(t, 5, updates.Security)
+}
+
+func TestPackageUpdateCollectorDnf(t *testing.T) {
+	root, err := ioutil.TempDir("", "packages")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "var/cache/dnf"), 0755))
+
+	var calls [][]string
+	collector := NewPackageUpdateCollector()
+	collector.root = root
+	collector.command = func(name string, args ...string) ([]byte, int, error) {
+		calls = append(calls, append([]string{name}, args...))
+
+		switch {
+		case name == "needs-restarting":
+			return []byte("Core libraries or services have been updated since boot-up:\n  * kernel\n  * systemd\n\nReboot is required to fully utilize these updates.\n"), 1, nil
+		case args[3] == "check-update":
+			return []byte("\nkernel.x86_64    5.14.0-284.el9    baseos\nopenssl.x86_64   1:3.0.7-6.el9     baseos\nObsoleting Packages\nold.x86_64   1.0   baseos\n"), 100, nil
+		default:
+			return []byte("RHSA-2023:1 Important/Sec. kernel-5.14.0-284.el9.x86_64\nRHSA-2023:2 Important/Sec. kernel-5.14.0-284.el9.x86_64\nRHSA-2023:3 Moderate/Sec. openssl-1:3.0.7-6.el9.x86_64\n"), 0, nil
+		}
+	}
+
+	updates, err := collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, "dnf", updates.Manager)
+	assert.Equal(t, 2, updates.Pending)
+	assert.Equal(t, 2, updates.Security)
+	assert.Equal(t, []string{"kernel", "systemd"}, updates.RestartRequired)
+	assert.Equal(t, []string{"dnf", "-C", "-q", "--setopt=cachedir=" + filepath.Join(root, "var/cache/dnf"), "check-update"}, calls[0])
+
+	// unchanged metadata is served from cache
+	_, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Len(t, calls, 3)
+}
+
+func TestPackageUpdateCollectorDnfCacheUnreadable(t *testing.T) {
+	root, err := ioutil.TempDir("", "packages")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "var/cache/dnf"), 0755))
+
+	collector := NewPackageUpdateCollector()
+	collector.root = root
+	collector.command = func(name string, args ...string) ([]byte, int, error) {
+		return []byte("Error: Cache-only enabled but no cache for 'baseos'\n"), 1, nil
+	}
+
+	_, err = collector.Collect()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the agent user can't read the system package cache in "+filepath.Join(root, "var/cache/dnf"))
+	assert.Contains(t, err.Error(), "Cache-only enabled but no cache for 'baseos'")
+}
@@ -0,0 +1,250 @@
+package collectors
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const phpFPMStatusTimeout = 2 * time.Second
+
+// phpFPMConfigGlobs lists where distributions keep PHP-FPM pool configuration
+var phpFPMConfigGlobs = []string{
+	"/etc/php/*/fpm/pool.d/*.conf",
+	"/etc/php-fpm.d/*.conf",
+	"/etc/opt/remi/php*/php-fpm.d/*.conf",
+	"/usr/local/etc/php-fpm.d/*.conf",
+}
+
+// phpFPMEndpoint describes where a pool's status page can be requested
+type phpFPMEndpoint struct {
+	pool       string
+	network    string
+	address    string
+	statusPath string
+}
+
+// phpFPMStatus represents the JSON output of the PHP-FPM status page
+type phpFPMStatus struct {
+	Pool               string `json:"pool"`
+	ProcessManager     string `json:"process manager"`
+	StartSince         uint64 `json:"start since"`
+	AcceptedConn       uint64 `json:"accepted conn"`
+	ListenQueue        uint64 `json:"listen queue"`
+	MaxListenQueue     uint64 `json:"max listen queue"`
+	ListenQueueLen     uint64 `json:"listen queue len"`
+	IdleProcesses      uint64 `json:"idle processes"`
+	ActiveProcesses    uint64 `json:"active processes"`
+	TotalProcesses     uint64 `json:"total processes"`
+	MaxActiveProcesses uint64 `json:"max active processes"`
+	MaxChildrenReached uint64 `json:"max children reached"`
+	SlowRequests       uint64 `json:"slow requests"`
+}
+
+// PHPFPMCollector queries PHP-FPM pool status pages over FastCGI
+type PHPFPMCollector struct {
+	endpoints []phpFPMEndpoint
+}
+
+// NewPHPFPMCollector creates a new instance of `PHPFPMCollector`.
+// Pools are discovered from PHP-FPM configuration when no addresses are given.
+func NewPHPFPMCollector(addresses []string, statusPath string) *PHPFPMCollector {
+	endpoints := make([]phpFPMEndpoint, 0, len(addresses))
+	for _, address := range addresses {
+		endpoint := newPHPFPMEndpoint(address)
+		endpoint.statusPath = statusPath
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return &PHPFPMCollector{endpoints}
+}
+
+// Collect returns the status of every reachable pool
+func (c *PHPFPMCollector) Collect() ([]metrics.PHPFPMPool, error) {
+	endpoints := c.endpoints
+	if len(endpoints) == 0 {
+		endpoints = discoverPHPFPMEndpoints(phpFPMConfigGlobs)
+	}
+
+	if len(endpoints) == 0 {
+		return nil, errors.New("No PHP-FPM pools with a status page found")
+	}
+
+	var lastErr error
+	pools := make([]metrics.PHPFPMPool, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		pool, err := queryPHPFPMStatus(endpoint)
+		if err != nil {
+			log.Trace().Err(err).Str("address", endpoint.address).Msg("Failed to fetch PHP-FPM status")
+			lastErr = err
+
+			continue
+		}
+
+		pools = append(pools, *pool)
+	}
+
+	if len(pools) == 0 {
+		return nil, errors.Wrap(lastErr, "Failed to fetch PHP-FPM status")
+	}
+
+	return pools, nil
+}
+
+func queryPHPFPMStatus(endpoint phpFPMEndpoint) (*metrics.PHPFPMPool, error) {
+	body, err := fastCGIGet(endpoint.network, endpoint.address, map[string]string{
+		"GATEWAY_INTERFACE": "CGI/1.1",
+		"REQUEST_METHOD":    "GET",
+		"SCRIPT_NAME":       endpoint.statusPath,
+		"SCRIPT_FILENAME":   endpoint.statusPath,
+		"REQUEST_URI":       endpoint.statusPath + "?json",
+		"QUERY_STRING":      "json",
+		"SERVER_PROTOCOL":   "HTTP/1.1",
+	}, phpFPMStatusTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &phpFPMStatus{}
+	if err := json.Unmarshal(body, status); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse PHP-FPM status")
+	}
+
+	name := status.Pool
+	if len(name) == 0 {
+		name = endpoint.pool
+	}
+
+	return &metrics.PHPFPMPool{
+		Name:                name,
+		Address:             endpoint.address,
+		ProcessManager:      status.ProcessManager,
+		StartSince:          status.StartSince,
+		AcceptedConnections: status.AcceptedConn,
+		ListenQueue:         status.ListenQueue,
+		MaxListenQueue:      status.MaxListenQueue,
+		ListenQueueLength:   status.ListenQueueLen,
+		IdleProcesses:       status.IdleProcesses,
+		ActiveProcesses:     status.ActiveProcesses,
+		TotalProcesses:      status.TotalProcesses,
+		MaxActiveProcesses:  status.MaxActiveProcesses,
+		MaxChildrenReached:  status.MaxChildrenReached,
+		SlowRequests:        status.SlowRequests,
+	}, nil
+}
+
+// newPHPFPMEndpoint maps a `listen` value (socket path, port or host:port) to a dialable address
+func newPHPFPMEndpoint(listen string) phpFPMEndpoint {
+	listen = strings.TrimPrefix(listen, "unix:")
+	if strings.HasPrefix(listen, "/") {
+		return phpFPMEndpoint{network: "unix", address: listen}
+	}
+
+	listen = strings.TrimPrefix(listen, "tcp://")
+	if !strings.Contains(listen, ":") {
+		return phpFPMEndpoint{network: "tcp", address: "127.0.0.1:" + listen}
+	}
+
+	i := strings.LastIndex(listen, ":")
+	host := listen[:i]
+	switch host {
+	case "", "*", "0.0.0.0", "[::]":
+		host = "127.0.0.1"
+	}
+
+	return phpFPMEndpoint{network: "tcp", address: host + listen[i:]}
+}
+
+// discoverPHPFPMEndpoints reads pool configuration files and returns pools that expose `pm.status_path`
+func discoverPHPFPMEndpoints(globs []string) []phpFPMEndpoint {
+	var endpoints []phpFPMEndpoint
+	for _, pattern := range globs {
+		files, _ := filepath.Glob(pattern)
+		for _, file := range files {
+			pools, err := parsePHPFPMConfig(file)
+			if err != nil {
+				log.Trace().Err(err).Str("file", file).Msg("Failed to parse PHP-FPM config")
+
+				continue
+			}
+
+			endpoints = append(endpoints, pools...)
+		}
+	}
+
+	return endpoints
+}
+
+func parsePHPFPMConfig(path string) ([]phpFPMEndpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	type pool struct {
+		name, listen, statusPath string
+	}
+
+	var pools []*pool
+	var current *pool
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' && strings.HasSuffix(line, "]") {
+			current = nil
+			if name := line[1 : len(line)-1]; name != "global" {
+				current = &pool{name: name}
+				pools = append(pools, current)
+			}
+
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if current == nil || len(kv) != 2 {
+			continue
+		}
+
+		value := strings.Trim(strings.TrimSpace(kv[1]), `"'`)
+		value = strings.Replace(value, "$pool", current.name, -1)
+
+		switch strings.TrimSpace(kv[0]) {
+		case "listen":
+			current.listen = value
+		case "pm.status_path":
+			current.statusPath = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]phpFPMEndpoint, 0, len(pools))
+	for _, p := range pools {
+		if len(p.listen) == 0 || len(p.statusPath) == 0 {
+			continue
+		}
+
+		endpoint := newPHPFPMEndpoint(p.listen)
+		endpoint.pool = p.name
+		endpoint.statusPath = p.statusPath
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints, nil
+}
@@ -0,0 +1,80 @@
+package collectors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePHPFPMConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "php-fpm")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "www.conf")
+	config := `
+[global]
+pid = /run/php/php7.4-fpm.pid
+
+[www]
+; comment
+listen = /run/php/php7.4-fpm-$pool.sock
+pm.status_path = /status
+
+[api]
+listen = 9001
+pm.status_path = /fpm-status
+
+[no-status]
+listen = 0.0.0.0:9002
+`
+	assert.NoError(t, ioutil.WriteFile(path, []byte(config), 0644))
+
+	endpoints := discoverPHPFPMEndpoints([]string{filepath.Join(dir, "*.conf")})
+	assert.Equal(t, []phpFPMEndpoint{
+		{pool: "www", network: "unix", address: "/run/php/php7.4-fpm-www.sock", statusPath: "/status"},
+		{pool: "api", network: "tcp", address: "127.0.0.1:9001", statusPath: "/fpm-status"},
+	}, endpoints)
+}
+
+func TestNewPHPFPMEndpoint(t *testing.T) {
+	assert.Equal(t, phpFPMEndpoint{network: "unix", address: "/run/php.sock"}, newPHPFPMEndpoint("unix:/run/php.sock"))
+	assert.Equal(t, phpFPMEndpoint{network: "tcp", address: "127.0.0.1:9000"}, newPHPFPMEndpoint("[::]:9000"))
+	assert.Equal(t, phpFPMEndpoint{network: "tcp", address: "10.0.0.2:9000"}, newPHPFPMEndpoint("10.0.0.2:9000"))
+}
+
+func TestPHPFPMCollector(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	go fcgi.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/status" || r.URL.RawQuery != "json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		fmt.Fprint(w, `{"pool":"www","process manager":"dynamic","start since":120,"accepted conn":10,`+
+			`"listen queue":2,"max listen queue":5,"listen queue len":128,"idle processes":1,`+
+			`"active processes":4,"total processes":5,"max active processes":5,`+
+			`"max children reached":3,"slow requests":1}`)
+	}))
+
+	pools, err := NewPHPFPMCollector([]string{listener.Addr().String()}, "/status").Collect()
+	assert.NoError(t, err)
+	assert.Len(t, pools, 1)
+	assert.Equal(t, "www", pools[0].Name)
+	assert.Equal(t, uint64(4), pools[0].ActiveProcesses)
+	assert.Equal(t, uint64(2), pools[0].ListenQueue)
+	assert.Equal(t, uint64(3), pools[0].MaxChildrenReached)
+
+	_, err = NewPHPFPMCollector([]string{listener.Addr().String()}, "/missing").Collect()
+	assert.Error(t, err)
+}
@@ -0,0 +1,105 @@
+package collectors
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// pressureResources lists the files read from /proc/pressure
+var pressureResources = []string{"cpu", "memory", "io"}
+
+// readPressure reads pressure stall information from the given directory.
+// Stall totals are reported as deltas against `previous`, which is updated in place.
+// It returns nil when the kernel doesn't expose PSI.
+func readPressure(dir string, previous map[string]uint64) (*metrics.ServerPressure, error) {
+	resources := make(map[string]*metrics.PressureResource)
+
+	for _, name := range pressureResources {
+		resource, err := readPressureFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		for kind, stall := range map[string]*metrics.PressureStall{"some": resource.Some, "full": resource.Full} {
+			if stall == nil {
+				continue
+			}
+
+			key := name + "." + kind
+			total := stall.Total
+			if last, ok := previous[key]; ok {
+				stall.Total = counterDelta(total, last)
+			} else {
+				stall.Total = 0
+			}
+			previous[key] = total
+		}
+
+		resources[name] = resource
+	}
+
+	if len(resources) == 0 {
+		return nil, nil
+	}
+
+	return &metrics.ServerPressure{
+		CPU:    resources["cpu"],
+		Memory: resources["memory"],
+		IO:     resources["io"],
+	}, nil
+}
+
+// readPressureFile parses lines like "some avg10=0.00 avg60=0.00 avg300=0.00 total=0"
+func readPressureFile(path string) (*metrics.PressureResource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	resource := &metrics.PressureResource{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		stall := &metrics.PressureStall{}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+
+			switch kv[0] {
+			case "avg10":
+				stall.Avg10, _ = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				stall.Avg60, _ = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				stall.Avg300, _ = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				stall.Total, _ = strconv.ParseUint(kv[1], 10, 64)
+			}
+		}
+
+		switch fields[0] {
+		case "some":
+			resource.Some = stall
+		case "full":
+			resource.Full = stall
+		}
+	}
+
+	return resource, scanner.Err()
+}
@@ -0,0 +1,47 @@
+package collectors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadPressure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pressure")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	write("cpu", "some avg10=1.50 avg60=0.75 avg300=0.10 total=1000\n")
+	write("memory", "some avg10=0.00 avg60=0.00 avg300=0.00 total=50\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=20\n")
+
+	previous := make(map[string]uint64)
+	pressure, err := readPressure(dir, previous)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, pressure.CPU.Some.Avg10)
+	assert.Equal(t, 0.1, pressure.CPU.Some.Avg300)
+	assert.Nil(t, pressure.CPU.Full)
+	assert.Equal(t, uint64(0), pressure.CPU.Some.Total)
+	assert.Nil(t, pressure.IO)
+
+	write("cpu", "some avg10=1.50 avg60=0.75 avg300=0.10 total=1600\n")
+	write("memory", "some avg10=0.00 avg60=0.00 avg300=0.00 total=80\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=20\n")
+
+	pressure, err = readPressure(dir, previous)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(600), pressure.CPU.Some.Total)
+	assert.Equal(t, uint64(30), pressure.Memory.Some.Total)
+	assert.Equal(t, uint64(0), pressure.Memory.Full.Total)
+}
+
+func TestReadPressureUnavailable(t *testing.T) {
+	pressure, err := readPressure(filepath.Join(os.TempDir(), "missing-pressure-dir"), make(map[string]uint64))
+	assert.NoError(t, err)
+	assert.Nil(t, pressure)
+}
@@ -0,0 +1,50 @@
+package collectors
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// hostProc returns a path inside the procfs mount, honouring `HOST_PROC`
+func hostProc(parts ...string) string {
+	return hostPath("HOST_PROC", "/proc", parts...)
+}
+
+func hostPath(env, fallback string, parts ...string) string {
+	root := os.Getenv(env)
+	if len(root) == 0 {
+		root = fallback
+	}
+
+	return filepath.Join(append([]string{root}, parts...)...)
+}
+
+// readKeyValueFile parses files made of "key value" lines, such as /proc/vmstat
+func readKeyValueFile(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		values[strings.TrimSuffix(fields[0], ":")] = value
+	}
+
+	return values, scanner.Err()
+}
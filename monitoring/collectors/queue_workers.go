@@ -0,0 +1,177 @@
+package collectors
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shirou/gopsutil/host"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// artisan commands that run queue workers
+var queueWorkerCommands = map[string]bool{
+	"queue:work":         true,
+	"queue:listen":       true,
+	"horizon":            true,
+	"horizon:supervisor": true,
+	"horizon:work":       true,
+}
+
+// QueueWorkerCollector observes Laravel queue worker processes
+type QueueWorkerCollector struct {
+	groups map[string]*queueWorkerGroup
+	first  bool
+}
+
+type queueWorkerGroup struct {
+	metric metrics.QueueWorkerGroup
+	pids   map[int32]bool
+}
+
+// NewQueueWorkerCollector creates a new instance of `QueueWorkerCollector`
+func NewQueueWorkerCollector() *QueueWorkerCollector {
+	return &QueueWorkerCollector{
+		groups: make(map[string]*queueWorkerGroup),
+		first:  true,
+	}
+}
+
+// Collect returns running queue workers grouped by connection and queue.
+// Groups stay in the report with zero workers once their processes are gone.
+func (c *QueueWorkerCollector) Collect() (*metrics.QueueWorkers, error) {
+	pids, err := listPids()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list processes")
+	}
+
+	bootTime, err := host.BootTime()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch boot time")
+	}
+
+	now := uint64(time.Now().Unix())
+	workers := make([]metrics.QueueWorker, 0)
+	current := make(map[string]map[int32]bool)
+
+	for _, pid := range pids {
+		cmdline, err := readProcCmdline(pid)
+		if err != nil {
+			continue
+		}
+
+		worker, ok := parseQueueWorker(cmdline)
+		if !ok {
+			continue
+		}
+
+		stat, err := readProcStat(pid)
+		if err != nil {
+			continue
+		}
+
+		worker.PID = pid
+		worker.MemoryRSS = stat.rss
+		if started := bootTime + stat.startTime/clockTicks; now > started {
+			worker.Uptime = now - started
+		}
+		workers = append(workers, *worker)
+
+		key := worker.Command + "|" + worker.Connection + "|" + worker.Queue
+		if current[key] == nil {
+			current[key] = make(map[int32]bool)
+		}
+		current[key][pid] = true
+
+		if c.groups[key] == nil {
+			c.groups[key] = &queueWorkerGroup{
+				metric: metrics.QueueWorkerGroup{
+					Command:    worker.Command,
+					Connection: worker.Connection,
+					Queue:      worker.Queue,
+				},
+				pids: make(map[int32]bool),
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(c.groups))
+	for key := range c.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	report := &metrics.QueueWorkers{Workers: workers}
+	for _, key := range keys {
+		group := c.groups[key]
+		group.metric.Workers = len(current[key])
+		group.metric.Restarts = 0
+
+		if !c.first {
+			for pid := range current[key] {
+				if !group.pids[pid] {
+					group.metric.Restarts++
+				}
+			}
+		}
+
+		group.pids = current[key]
+		report.Groups = append(report.Groups, group.metric)
+	}
+
+	c.first = false
+
+	return report, nil
+}
+
+// parseQueueWorker recognises command lines like
+// `php /var/www/artisan queue:work redis --queue=high,default --sleep=3`
+func parseQueueWorker(cmdline []string) (*metrics.QueueWorker, bool) {
+	for i, arg := range cmdline {
+		if filepath.Base(arg) != "artisan" || i+1 >= len(cmdline) {
+			continue
+		}
+
+		command := cmdline[i+1]
+		if !queueWorkerCommands[command] {
+			return nil, false
+		}
+
+		worker := &metrics.QueueWorker{Command: command}
+		var positional []string
+		args := cmdline[i+2:]
+		for j := 0; j < len(args); j++ {
+			switch {
+			case strings.HasPrefix(args[j], "--queue="):
+				worker.Queue = strings.TrimPrefix(args[j], "--queue=")
+			case args[j] == "--queue" && j+1 < len(args):
+				worker.Queue = args[j+1]
+				j++
+			case !strings.HasPrefix(args[j], "-"):
+				positional = append(positional, args[j])
+			}
+		}
+
+		// `horizon:supervisor <name> <connection>` is the only command with a leading name argument
+		if command == "horizon:supervisor" && len(positional) > 0 {
+			positional = positional[1:]
+		}
+		if len(positional) > 0 {
+			worker.Connection = positional[0]
+		}
+
+		// Horizon's master and supervisors don't process jobs themselves
+		if command == "queue:work" || command == "queue:listen" || command == "horizon:work" {
+			if len(worker.Queue) == 0 {
+				worker.Queue = "default"
+			}
+		}
+
+		return worker, true
+	}
+
+	return nil, false
+}
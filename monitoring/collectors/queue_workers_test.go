@@ -0,0 +1,88 @@
+package collectors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+func TestParseQueueWorker(t *testing.T) {
+	worker, ok := parseQueueWorker([]string{"php", "/var/www/artisan", "queue:work", "redis", "--queue=high,default", "--sleep=3"})
+	assert.True(t, ok)
+	assert.Equal(t, &metrics.QueueWorker{Command: "queue:work", Connection: "redis", Queue: "high,default"}, worker)
+
+	worker, ok = parseQueueWorker([]string{"php", "artisan", "queue:listen", "--queue", "emails"})
+	assert.True(t, ok)
+	assert.Equal(t, "emails", worker.Queue)
+	assert.Equal(t, "", worker.Connection)
+
+	worker, ok = parseQueueWorker([]string{"php", "artisan", "queue:work"})
+	assert.True(t, ok)
+	assert.Equal(t, "default", worker.Queue)
+
+	worker, ok = parseQueueWorker([]string{"php", "artisan", "horizon:supervisor", "host-a1b2:supervisor-1", "redis", "--queue=default"})
+	assert.True(t, ok)
+	assert.Equal(t, "redis", worker.Connection)
+
+	_, ok = parseQueueWorker([]string{"php", "artisan", "schedule:run"})
+	assert.False(t, ok)
+
+	_, ok = parseQueueWorker([]string{"nginx: worker process"})
+	assert.False(t, ok)
+}
+
+func TestQueueWorkerCollector(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "stat"), []byte("cpu 0 0 0 0\nbtime 1600000000\n"), 0644))
+
+	writeWorker := func(pid int, args ...string) {
+		path := filepath.Join(dir, fmt.Sprint(pid))
+		assert.NoError(t, os.MkdirAll(path, 0755))
+		stat := fmt.Sprintf("%d (php) S 1 1 1 0 -1 0 0 0 0 0 1 1 0 0 20 0 1 0 100 0 10 0", pid)
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(path, "stat"), []byte(stat), 0644))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(path, "cmdline"), []byte(strings.Join(args, "\x00")), 0644))
+	}
+
+	writeWorker(10, "php", "artisan", "queue:work", "redis", "--queue=default")
+	writeWorker(11, "php", "artisan", "queue:work", "redis", "--queue=default")
+	writeWorker(12, "/usr/sbin/nginx")
+
+	os.Setenv("HOST_PROC", dir)
+	defer os.Unsetenv("HOST_PROC")
+
+	collector := NewQueueWorkerCollector()
+	workers, err := collector.Collect()
+	assert.NoError(t, err)
+	assert.Len(t, workers.Workers, 2)
+	assert.Equal(t, uint64(10*os.Getpagesize()), workers.Workers[0].MemoryRSS)
+	assert.Equal(t, []metrics.QueueWorkerGroup{
+		{Command: "queue:work", Connection: "redis", Queue: "default", Workers: 2, Restarts: 0},
+	}, workers.Groups)
+
+	// one worker was replaced, the other one died
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "10")))
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "11")))
+	writeWorker(13, "php", "artisan", "queue:work", "redis", "--queue=default")
+
+	workers, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, []metrics.QueueWorkerGroup{
+		{Command: "queue:work", Connection: "redis", Queue: "default", Workers: 1, Restarts: 1},
+	}, workers.Groups)
+
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "13")))
+
+	workers, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, workers.Groups[0].Workers)
+}
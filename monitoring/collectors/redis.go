@@ -0,0 +1,169 @@
+package collectors
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const redisTimeout = 2 * time.Second
+
+// RedisConfig holds Redis connection and Laravel queue settings
+type RedisConfig struct {
+	Address   string // host:port or a unix socket path
+	Password  string
+	Database  int
+	KeyPrefix string // Laravel's `database.redis.options.prefix`
+	Queues    []string
+	// Horizon's `horizon.prefix`, Horizon isn't collected when empty
+	HorizonPrefix string
+}
+
+// RedisCollector reports Redis `INFO` stats and Laravel queue lengths
+type RedisCollector struct {
+	config   RedisConfig
+	pool     *redis.Pool
+	previous map[string]string
+}
+
+// NewRedisCollector creates a new instance of `RedisCollector`
+func NewRedisCollector(cfg RedisConfig) *RedisCollector {
+	network := "tcp"
+	if strings.HasPrefix(cfg.Address, "/") || strings.HasPrefix(cfg.Address, "unix:") {
+		network = "unix"
+		cfg.Address = strings.TrimPrefix(cfg.Address, "unix:")
+	}
+
+	return &RedisCollector{
+		config: cfg,
+		pool: &redis.Pool{
+			MaxIdle:     1,
+			IdleTimeout: 5 * time.Minute,
+			Dial: func() (redis.Conn, error) {
+				return redis.Dial(
+					network,
+					cfg.Address,
+					redis.DialPassword(cfg.Password),
+					redis.DialDatabase(cfg.Database),
+					redis.DialConnectTimeout(redisTimeout),
+					redis.DialReadTimeout(redisTimeout),
+					redis.DialWriteTimeout(redisTimeout),
+				)
+			},
+		},
+	}
+}
+
+// Collect returns Redis stats and queue lengths
+func (c *RedisCollector) Collect() (*metrics.Redis, error) {
+	conn := c.pool.Get()
+	defer conn.Close()
+
+	info, err := redis.String(conn.Do("INFO"))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch Redis INFO")
+	}
+
+	stats := parseRedisInfo(info)
+	number := func(key string) uint64 {
+		value, _ := strconv.ParseUint(stats[key], 10, 64)
+		return value
+	}
+	delta := func(key string) uint64 {
+		if c.previous == nil {
+			return 0
+		}
+		previous, _ := strconv.ParseUint(c.previous[key], 10, 64)
+
+		return counterDelta(number(key), previous)
+	}
+
+	r := &metrics.Redis{
+		Version:          stats["redis_version"],
+		Uptime:           number("uptime_in_seconds"),
+		UsedMemory:       number("used_memory"),
+		UsedMemoryRSS:    number("used_memory_rss"),
+		MaxMemory:        number("maxmemory"),
+		ConnectedClients: number("connected_clients"),
+		BlockedClients:   number("blocked_clients"),
+		OpsPerSecond:     number("instantaneous_ops_per_sec"),
+		EvictedKeys:      delta("evicted_keys"),
+		ExpiredKeys:      delta("expired_keys"),
+		KeyspaceHits:     delta("keyspace_hits"),
+		KeyspaceMisses:   delta("keyspace_misses"),
+		Keyspace:         make(map[string]uint64),
+	}
+	r.FragmentationRatio, _ = strconv.ParseFloat(stats["mem_fragmentation_ratio"], 64)
+	c.previous = stats
+
+	for key, value := range stats {
+		// db0:keys=1,expires=0,avg_ttl=0
+		if !strings.HasPrefix(key, "db") || !strings.HasPrefix(value, "keys=") {
+			continue
+		}
+		keys := strings.SplitN(strings.TrimPrefix(value, "keys="), ",", 2)[0]
+		r.Keyspace[key], _ = strconv.ParseUint(keys, 10, 64)
+	}
+
+	// INFO stats are still reported, their deltas are already based on this collection
+	r.Queues, err = c.queues(conn)
+	if err != nil {
+		log.Trace().Err(err).Msg("Failed to fetch Laravel queues")
+	}
+
+	return r, nil
+}
+
+// queues reads Laravel queue lengths in a single round trip
+func (c *RedisCollector) queues(conn redis.Conn) ([]metrics.RedisQueue, error) {
+	for _, name := range c.config.Queues {
+		key := c.config.KeyPrefix + "queues:" + name
+		_ = conn.Send("LLEN", key)
+		_ = conn.Send("ZCARD", key+":delayed")
+		_ = conn.Send("ZCARD", key+":reserved")
+	}
+
+	if err := conn.Flush(); err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch Laravel queue lengths")
+	}
+
+	queues := make([]metrics.RedisQueue, 0, len(c.config.Queues))
+	for _, name := range c.config.Queues {
+		queue := metrics.RedisQueue{Name: name}
+		for _, count := range []*uint64{&queue.Pending, &queue.Delayed, &queue.Reserved} {
+			value, err := redis.Uint64(conn.Receive())
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to fetch length of queue %s", name)
+			}
+			*count = value
+		}
+
+		queues = append(queues, queue)
+	}
+
+	return queues, nil
+}
+
+// parseRedisInfo parses `INFO` output into a flat map
+func parseRedisInfo(info string) map[string]string {
+	stats := make(map[string]string)
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) == 2 {
+			stats[kv[0]] = kv[1]
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,143 @@
+package collectors
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// redisStandIn is a minimal RESP server answering commands through `handler`
+func redisStandIn(t *testing.T, handler func(args []string) interface{}) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					args, err := readRESPCommand(r)
+					if err != nil {
+						return
+					}
+					writeRESP(conn, handler(args))
+				}
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String(), func() { listener.Close() }
+}
+
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	count, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+	args := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimSpace(arg))
+	}
+
+	return args, nil
+}
+
+func writeRESP(w io.Writer, reply interface{}) {
+	switch v := reply.(type) {
+	case nil:
+		fmt.Fprint(w, "$-1\r\n")
+	case error:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeRESP(w, item)
+		}
+	}
+}
+
+func TestRedisCollector(t *testing.T) {
+	evicted := 10
+	queuesFail := false
+	lengths := map[string]int{
+		"LLEN laravel_database_queues:default":           5,
+		"ZCARD laravel_database_queues:default:delayed":  2,
+		"ZCARD laravel_database_queues:default:reserved": 1,
+	}
+
+	address, stop := redisStandIn(t, func(args []string) interface{} {
+		switch args[0] {
+		case "INFO":
+			return fmt.Sprintf("# Server\r\nredis_version:6.0.9\r\nuptime_in_seconds:100\r\n"+
+				"# Memory\r\nused_memory:1024\r\nmem_fragmentation_ratio:1.5\r\n"+
+				"# Stats\r\ninstantaneous_ops_per_sec:42\r\nevicted_keys:%d\r\nkeyspace_hits:7\r\n"+
+				"# Keyspace\r\ndb0:keys=12,expires=1,avg_ttl=0\r\n", evicted)
+		case "LLEN", "ZCARD":
+			if queuesFail {
+				return fmt.Errorf("WRONGTYPE Operation against a key holding the wrong kind of value")
+			}
+			return lengths[strings.Join(args, " ")]
+		}
+
+		return fmt.Errorf("ERR unknown command '%s'", args[0])
+	})
+	defer stop()
+
+	collector := NewRedisCollector(RedisConfig{
+		Address:   address,
+		KeyPrefix: "laravel_database_",
+		Queues:    []string{"default", "emails"},
+	})
+
+	r, err := collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, "6.0.9", r.Version)
+	assert.Equal(t, uint64(1024), r.UsedMemory)
+	assert.Equal(t, 1.5, r.FragmentationRatio)
+	assert.Equal(t, uint64(42), r.OpsPerSecond)
+	assert.Equal(t, uint64(0), r.EvictedKeys)
+	assert.Equal(t, map[string]uint64{"db0": 12}, r.Keyspace)
+	assert.Len(t, r.Queues, 2)
+	assert.Equal(t, uint64(5), r.Queues[0].Pending)
+	assert.Equal(t, uint64(2), r.Queues[0].Delayed)
+	assert.Equal(t, uint64(1), r.Queues[0].Reserved)
+	assert.Equal(t, uint64(0), r.Queues[1].Pending)
+
+	evicted = 15
+	r, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), r.EvictedKeys)
+
+	// stats are reported without queues
+	evicted = 17
+	queuesFail = true
+	r, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), r.EvictedKeys)
+	assert.Empty(t, r.Queues)
+}
@@ -0,0 +1,130 @@
+package collectors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/rs/zerolog/log"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const (
+	runtimeDocker     = "docker"
+	runtimePodman     = "podman"
+	runtimeContainerd = "containerd"
+
+	runtimeDetectTimeout = 5 * time.Second
+)
+
+// ContainerRuntime lists containers with their resource usage
+type ContainerRuntime interface {
+	// Name returns the runtime name, used as `Container.Type`
+	Name() string
+	FetchContainers() ([]metrics.Container, error)
+	Close()
+}
+
+// RuntimeConfig holds container runtime socket locations, empty values are auto-detected
+type RuntimeConfig struct {
+	PodmanSocket     string
+	ContainerdSocket string
+	CgroupStats      bool // Read Docker API runtimes' container usage from cgroups
+}
+
+// hostRun returns a path inside the host's /run, honouring `HOST_RUN` like gopsutil
+func hostRun(parts ...string) string {
+	return hostPath("HOST_RUN", "/run", parts...)
+}
+
+// DetectRuntimes returns a client for every container runtime with a socket on this host.
+// Sockets which aren't configured are looked up in their default location under `hostRun()`.
+func DetectRuntimes(cfg RuntimeConfig) []ContainerRuntime {
+	var runtimes []ContainerRuntime
+	// the same daemon is often reachable through several sockets, e.g. podman-docker's /var/run/docker.sock
+	seen := make(map[string]bool)
+
+	dockerHost := os.Getenv("DOCKER_HOST")
+	if len(dockerHost) == 0 {
+		dockerHost = client.DefaultDockerHost
+		if len(os.Getenv("HOST_RUN")) > 0 {
+			dockerHost = "unix://" + hostRun("docker.sock")
+		}
+	}
+
+	sockets := []string{dockerHost}
+	if len(cfg.PodmanSocket) > 0 {
+		sockets = append(sockets, "unix://"+cfg.PodmanSocket)
+	} else {
+		rootless, _ := filepath.Glob(hostRun("user", "*", "podman", "podman.sock"))
+		for _, socket := range append([]string{hostRun("podman", "podman.sock")}, rootless...) {
+			sockets = append(sockets, "unix://"+socket)
+		}
+	}
+
+	for i, host := range sockets {
+		if path := strings.TrimPrefix(host, "unix://"); path != host {
+			resolved, err := filepath.EvalSymlinks(path)
+			// without its socket, e.g. when it isn't mounted into the agent's container,
+			// Docker is kept when its containers are found in cgroups and reported from there
+			if err != nil && i == 0 && len(dockerCgroups()) > 0 {
+				resolved, err = path, nil
+			}
+			if err != nil || seen[resolved] {
+				continue
+			}
+			seen[resolved] = true
+		}
+
+		// Docker is kept even when the daemon is down, it may start after the agent
+		dockerClient, err := newDockerCompatibleClient(host, i > 0)
+		if err != nil {
+			log.Trace().Err(err).Msgf("No container runtime at %s", host)
+			continue
+		}
+		dockerClient.cgroupStats = cfg.CgroupStats
+		runtimes = append(runtimes, dockerClient)
+	}
+
+	containerdSocket := cfg.ContainerdSocket
+	if len(containerdSocket) == 0 {
+		containerdSocket = hostRun("containerd", "containerd.sock")
+	}
+	if _, err := os.Stat(containerdSocket); err == nil {
+		runtimes = append(runtimes, NewContainerdRuntime(containerdSocket))
+	}
+
+	return runtimes
+}
+
+// newDockerCompatibleClient connects to a Docker Engine API and tells Docker and Podman apart
+func newDockerCompatibleClient(host string, requireDaemon bool) (*DockerClient, error) {
+	apiClient, err := client.NewClientWithOpts(client.WithHost(host), client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), runtimeDetectTimeout)
+	defer cancel()
+
+	runtime := runtimeDocker
+	version, err := apiClient.ServerVersion(ctx)
+	if err != nil && requireDaemon {
+		apiClient.Close()
+		return nil, err
+	}
+
+	if err == nil {
+		for _, component := range version.Components {
+			if strings.Contains(component.Name, "Podman") {
+				runtime = runtimePodman
+			}
+		}
+	}
+
+	return newDockerClient(apiClient, runtime), nil
+}
@@ -0,0 +1,168 @@
+package collectors
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const (
+	// SchedulerSourceProcess marks runs observed in the process table
+	SchedulerSourceProcess = "process"
+	// SchedulerSourceSocket marks runs reported by the application over the socket
+	SchedulerSourceSocket = "socket"
+
+	// `schedule:run` usually exits within a second when nothing is due
+	schedulerPollInterval = 500 * time.Millisecond
+)
+
+// SchedulerMonitor detects whether `artisan schedule:run` executes every minute
+type SchedulerMonitor struct {
+	window    time.Duration
+	startedAt time.Time
+	lastRunAt time.Time
+	source    string
+	missed    bool
+	events    []metrics.SchedulerEvent
+	knownPids map[int32]bool
+	mutex     sync.Mutex
+	stop      chan struct{}
+}
+
+// NewSchedulerMonitor creates a new instance of `SchedulerMonitor`.
+// A missed schedule is reported when no run is seen within `window`.
+func NewSchedulerMonitor(window time.Duration) *SchedulerMonitor {
+	return &SchedulerMonitor{
+		window:    window,
+		startedAt: time.Now(),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Start watching the process table for `schedule:run`
+func (sm *SchedulerMonitor) Start() {
+	ticker := time.NewTicker(schedulerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sm.stop:
+			return
+		case t := <-ticker.C:
+			sm.scanProcesses(t)
+			sm.check(t)
+		}
+	}
+}
+
+// Stop the scheduler monitor
+func (sm *SchedulerMonitor) Stop() {
+	sm.stop <- struct{}{}
+}
+
+// Heartbeat records a scheduler run
+func (sm *SchedulerMonitor) Heartbeat(source string, at time.Time) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if at.Before(sm.lastRunAt) {
+		return
+	}
+
+	if sm.missed {
+		log.Info().Str("source", source).Msg("Laravel scheduler resumed")
+		sm.events = append(sm.events, metrics.SchedulerEvent{
+			Type:       metrics.SchedulerEventResumed,
+			LastRunAt:  at.Format(time.RFC3339),
+			DetectedAt: at.Format(time.RFC3339),
+		})
+	}
+
+	sm.lastRunAt = at
+	sm.source = source
+	sm.missed = false
+}
+
+// Collect returns scheduler health and drains events collected since the previous call
+func (sm *SchedulerMonitor) Collect() *metrics.Scheduler {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	scheduler := &metrics.Scheduler{
+		LastRunAt: metrics.FormatTime(sm.lastRunAt),
+		Source:    sm.source,
+		Missed:    sm.missed,
+		Events:    sm.events,
+	}
+	sm.events = nil
+
+	return scheduler
+}
+
+// check emits a single missed event per gap between runs
+func (sm *SchedulerMonitor) check(now time.Time) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	reference := sm.lastRunAt
+	if reference.IsZero() {
+		reference = sm.startedAt
+	}
+
+	if sm.missed || now.Sub(reference) <= sm.window {
+		return
+	}
+
+	log.Warn().Str("last_run_at", metrics.FormatTime(sm.lastRunAt)).Msg("Laravel scheduler missed its schedule")
+
+	sm.missed = true
+	sm.events = append(sm.events, metrics.SchedulerEvent{
+		Type:       metrics.SchedulerEventMissed,
+		LastRunAt:  metrics.FormatTime(sm.lastRunAt),
+		DetectedAt: now.Format(time.RFC3339),
+	})
+}
+
+// scanProcesses looks for `schedule:run` among processes started since the previous scan
+func (sm *SchedulerMonitor) scanProcesses(now time.Time) {
+	pids, err := listPids()
+	if err != nil {
+		return
+	}
+
+	known := make(map[int32]bool, len(pids))
+	for _, pid := range pids {
+		known[pid] = true
+		if sm.knownPids[pid] {
+			continue
+		}
+
+		// comm is short and cheap to read, only PHP processes have their command line read
+		comm, err := readProcComm(pid)
+		if err != nil || !strings.Contains(comm, "php") {
+			continue
+		}
+
+		cmdline, err := readProcCmdline(pid)
+		if err == nil && isScheduleRun(cmdline) {
+			sm.Heartbeat(SchedulerSourceProcess, now)
+		}
+	}
+
+	sm.knownPids = known
+}
+
+func isScheduleRun(cmdline []string) bool {
+	for i, arg := range cmdline {
+		if filepath.Base(arg) == "artisan" && i+1 < len(cmdline) {
+			return cmdline[i+1] == "schedule:run"
+		}
+	}
+
+	return false
+}
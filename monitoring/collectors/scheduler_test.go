@@ -0,0 +1,87 @@
+package collectors
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+func TestSchedulerMonitor(t *testing.T) {
+	monitor := NewSchedulerMonitor(2 * time.Minute)
+	start := monitor.startedAt
+
+	monitor.check(start.Add(time.Minute))
+	scheduler := monitor.Collect()
+	assert.False(t, scheduler.Missed)
+	assert.Empty(t, scheduler.LastRunAt)
+	assert.Empty(t, scheduler.Events)
+
+	monitor.Heartbeat(SchedulerSourceSocket, start.Add(time.Minute))
+	monitor.check(start.Add(2 * time.Minute))
+	assert.False(t, monitor.Collect().Missed)
+
+	// only a single event is emitted per gap
+	monitor.check(start.Add(4 * time.Minute))
+	monitor.check(start.Add(5 * time.Minute))
+	scheduler = monitor.Collect()
+	assert.True(t, scheduler.Missed)
+	assert.Equal(t, SchedulerSourceSocket, scheduler.Source)
+	assert.Len(t, scheduler.Events, 1)
+	assert.Equal(t, metrics.SchedulerEventMissed, scheduler.Events[0].Type)
+	assert.Equal(t, start.Add(time.Minute).Format(time.RFC3339), scheduler.Events[0].LastRunAt)
+
+	monitor.Heartbeat(SchedulerSourceProcess, start.Add(6*time.Minute))
+	scheduler = monitor.Collect()
+	assert.False(t, scheduler.Missed)
+	assert.Equal(t, SchedulerSourceProcess, scheduler.Source)
+	assert.Len(t, scheduler.Events, 1)
+	assert.Equal(t, metrics.SchedulerEventResumed, scheduler.Events[0].Type)
+}
+
+func TestSchedulerMonitorWithoutRuns(t *testing.T) {
+	monitor := NewSchedulerMonitor(time.Minute)
+	monitor.check(monitor.startedAt.Add(2 * time.Minute))
+
+	scheduler := monitor.Collect()
+	assert.True(t, scheduler.Missed)
+	assert.Empty(t, scheduler.Events[0].LastRunAt)
+}
+
+func TestSchedulerMonitorScanProcesses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	writeProcess := func(pid, comm, cmdline string) {
+		assert.NoError(t, os.MkdirAll(filepath.Join(dir, pid), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, pid, "comm"), []byte(comm+"\n"), 0644))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, pid, "cmdline"), []byte(cmdline), 0644))
+	}
+
+	os.Setenv("HOST_PROC", dir)
+	defer os.Unsetenv("HOST_PROC")
+
+	// only PHP processes have their command line checked
+	writeProcess("10", "node", "node\x00artisan\x00schedule:run\x00")
+	monitor := NewSchedulerMonitor(time.Minute)
+	monitor.scanProcesses(time.Now())
+	assert.Empty(t, monitor.Collect().LastRunAt)
+
+	writeProcess("11", "php8.2", "/usr/bin/php8.2\x00artisan\x00schedule:run\x00")
+	monitor.scanProcesses(time.Now())
+	scheduler := monitor.Collect()
+	assert.NotEmpty(t, scheduler.LastRunAt)
+	assert.Equal(t, SchedulerSourceProcess, scheduler.Source)
+}
+
+func TestIsScheduleRun(t *testing.T) {
+	assert.True(t, isScheduleRun([]string{"/usr/bin/php", "/var/www/artisan", "schedule:run"}))
+	assert.False(t, isScheduleRun([]string{"php", "artisan", "schedule:list"}))
+	assert.False(t, isScheduleRun([]string{"cron"}))
+}
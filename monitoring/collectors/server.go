@@ -14,73 +14,280 @@ import (
 	"github.com/shirou/gopsutil/load"
 	"github.com/shirou/gopsutil/mem"
 
+	"github.com/larashed/agent-go/config"
 	"github.com/larashed/agent-go/monitoring/buckets"
 	"github.com/larashed/agent-go/monitoring/metrics"
 )
 
+// a flapping unit shouldn't turn into back to back collections, service changes are reported at most this often
+const serviceChangeCollectInterval = 10 * time.Second
+
 // ServerMetricCollector defines a server resource collector
 type ServerMetricCollector struct {
-	inDocker             bool
-	dockerClient         *DockerClient
+	runtimes             []ContainerRuntime
+	volumeSizer          *VolumeSizer
 	bucket               *buckets.ServerMetricBucket
 	serverMetricInterval time.Duration
 	hostname             string
 	stop                 chan int
+	collectNow           chan struct{}
+	collectedAt          time.Time
+	lastVMStat           *vmStat
+	lastPressure         map[string]uint64
+	processCollector     *ProcessCollector
+	phpFPMCollector      *PHPFPMCollector
+	webServerCollector   *WebServerCollector
+	mysqlCollector       *MySQLCollector
+	redisCollector       *RedisCollector
+	queueWorkerCollector *QueueWorkerCollector
+	schedulerMonitor     *SchedulerMonitor
+	certificateCollector *CertificateCollector
+	packageCollector     *PackageUpdateCollector
+	serviceCollector     *ServiceCollector
+	kubeletClient        *KubeletClient
 }
 
 // NewServerMetricCollector creates a new instance of `ServerMetricCollector`
 func NewServerMetricCollector(
 	bucket *buckets.ServerMetricBucket,
 	serverMetricInterval time.Duration,
-	hostname string,
-	inDocker bool) *ServerMetricCollector {
-	dockerClient, err := NewDockerClient()
-	if err != nil {
-		log.Trace().Err(err)
+	cfg *config.Config) *ServerMetricCollector {
+	var err error
+
+	collector := &ServerMetricCollector{
+		bucket:               bucket,
+		serverMetricInterval: serverMetricInterval,
+		hostname:             cfg.Hostname,
+		stop:                 make(chan int, 0),
+		collectNow:           make(chan struct{}, 1),
+		lastPressure:         make(map[string]uint64),
+	}
+
+	if !cfg.InDocker && cfg.CollectServices {
+		collector.serviceCollector = NewServiceCollector(cfg.SystemdUnits)
+	}
+
+	// a node agent sees the host's container runtimes through mounted sockets and state directories
+	if (!cfg.InDocker || cfg.Kubernetes) && cfg.CollectContainers {
+		collector.runtimes = DetectRuntimes(RuntimeConfig{
+			PodmanSocket:     cfg.PodmanSocket,
+			ContainerdSocket: cfg.ContainerdSocket,
+			CgroupStats:      cfg.CgroupContainerStats,
+		})
+	}
+
+	if cfg.Kubernetes && cfg.CollectContainers {
+		collector.kubeletClient = NewKubeletClient(KubeletConfig{
+			URL:         cfg.KubeletURL,
+			TokenFile:   DefaultKubeletTokenFile,
+			CAFile:      DefaultKubeletCAFile,
+			InsecureTLS: cfg.KubeletInsecureTLS,
+		})
+	}
+
+	if cfg.VolumeSizeInterval > 0 {
+		collector.volumeSizer = NewVolumeSizer(cfg.VolumeSizeInterval, cfg.VolumeSizeTimeout)
+		for _, runtime := range collector.runtimes {
+			if dockerClient, ok := runtime.(*DockerClient); ok {
+				dockerClient.volumes = collector.volumeSizer
+			}
+		}
+	}
+
+	if cfg.CollectProcesses {
+		collector.processCollector = NewProcessCollector(cfg.ProcessLimit, cfg.RedactProcessCmdline)
+	}
+
+	if cfg.CollectPHPFPM {
+		collector.phpFPMCollector = NewPHPFPMCollector(cfg.PHPFPMEndpoints, cfg.PHPFPMStatusPath)
+	}
+
+	if len(cfg.NginxStatusURLs) > 0 || len(cfg.ApacheStatusURLs) > 0 {
+		collector.webServerCollector = NewWebServerCollector(cfg.NginxStatusURLs, cfg.ApacheStatusURLs)
+	}
+
+	if len(cfg.MySQLDSN) > 0 {
+		collector.mysqlCollector, err = NewMySQLCollector(cfg.MySQLDSN)
+		if err != nil {
+			log.Error().Err(err).Msg("MySQL collection disabled")
+		}
+	}
+
+	if len(cfg.RedisAddress) > 0 {
+		collector.redisCollector = NewRedisCollector(RedisConfig{
+			Address:   cfg.RedisAddress,
+			Password:  cfg.RedisPassword,
+			Database:  cfg.RedisDatabase,
+			KeyPrefix: cfg.RedisKeyPrefix,
+			Queues:    cfg.RedisQueues,
+
+			HorizonPrefix: cfg.HorizonPrefix,
+		})
+	}
+
+	if cfg.CollectQueueWorkers {
+		collector.queueWorkerCollector = NewQueueWorkerCollector()
 	}
 
-	return &ServerMetricCollector{
-		inDocker,
-		dockerClient,
-		bucket,
-		serverMetricInterval,
-		hostname,
-		make(chan int, 0),
+	if cfg.MonitorScheduler {
+		collector.schedulerMonitor = NewSchedulerMonitor(cfg.SchedulerMissedWindow)
 	}
+
+	if len(cfg.TLSEndpoints) > 0 || len(cfg.TLSCertificateFiles) > 0 {
+		collector.certificateCollector = NewCertificateCollector(cfg.TLSEndpoints, cfg.TLSCertificateFiles)
+	}
+
+	if cfg.CollectPackageUpdates {
+		collector.packageCollector = NewPackageUpdateCollector()
+	}
+
+	return collector
 }
 
 // Start server metric collection
 func (smc *ServerMetricCollector) Start() {
+	if smc.schedulerMonitor != nil {
+		go smc.schedulerMonitor.Start()
+	}
+
+	if smc.volumeSizer != nil {
+		go smc.volumeSizer.Start()
+	}
+
+	var serviceChanges <-chan struct{}
+	if smc.serviceCollector != nil {
+		go smc.serviceCollector.Start()
+		serviceChanges = smc.serviceCollector.Changes()
+	}
+
 	ticker := time.NewTicker(smc.serverMetricInterval)
 	defer ticker.Stop()
 
 	// lets measure at start
-	metric, err := smc.fetchServerMetrics()
-	if err != nil {
-		log.Error().Msgf("Failed to collect server metrics: %v", err)
-	} else {
-		smc.bucket.Add(metric)
-	}
+	smc.collect()
+
+	// pending collection reporting service changes, any collection in the meantime includes them
+	var serviceCollection <-chan time.Time
 
 	for {
 		select {
 		case <-smc.stop:
 			return
 		case <-ticker.C:
-			metric, err := smc.fetchServerMetrics()
-			if err != nil {
-				log.Error().Msgf("Failed to collect server metrics: %v", err)
-
-				continue
+			serviceCollection = nil
+			smc.collect()
+		case <-serviceChanges:
+			// report service failures before the next tick
+			if serviceCollection == nil {
+				serviceCollection = time.After(serviceChangeCollectInterval - time.Since(smc.collectedAt))
 			}
-			smc.bucket.Add(metric)
+		case <-serviceCollection:
+			serviceCollection = nil
+			smc.collect()
+		case <-smc.collectNow:
+			serviceCollection = nil
+			smc.collect()
 		}
 	}
 }
 
+// collect server metrics into the bucket
+func (smc *ServerMetricCollector) collect() {
+	smc.collectedAt = time.Now()
+
+	metric, err := smc.fetchServerMetrics()
+	if err != nil {
+		log.Error().Msgf("Failed to collect server metrics: %v", err)
+
+		return
+	}
+
+	smc.bucket.Add(metric)
+}
+
 // Stop server metric collection
 func (smc *ServerMetricCollector) Stop() {
 	smc.stop <- 1
+
+	if smc.schedulerMonitor != nil {
+		smc.schedulerMonitor.Stop()
+	}
+
+	if smc.serviceCollector != nil {
+		smc.serviceCollector.Stop()
+	}
+
+	if smc.volumeSizer != nil {
+		smc.volumeSizer.Stop()
+	}
+
+	for _, runtime := range smc.runtimes {
+		runtime.Close()
+	}
+}
+
+// CollectNow makes a started collector collect without waiting for the next tick
+func (smc *ServerMetricCollector) CollectNow() {
+	select {
+	case smc.collectNow <- struct{}{}:
+	default:
+		// a collection is already pending
+	}
+}
+
+// Collectors returns the names of enabled collectors
+func (smc *ServerMetricCollector) Collectors() []string {
+	names := []string{"server"}
+	if smc.serviceCollector != nil {
+		names = append(names, "services")
+	}
+	for _, runtime := range smc.runtimes {
+		names = append(names, "containers:"+runtime.Name())
+	}
+
+	for _, collector := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"kubernetes", smc.kubeletClient != nil},
+		{"volume-sizes", smc.volumeSizer != nil},
+		{"processes", smc.processCollector != nil},
+		{"php-fpm", smc.phpFPMCollector != nil},
+		{"web-servers", smc.webServerCollector != nil},
+		{"mysql", smc.mysqlCollector != nil},
+		{"redis", smc.redisCollector != nil},
+		{"queue-workers", smc.queueWorkerCollector != nil},
+		{"scheduler", smc.schedulerMonitor != nil},
+		{"certificates", smc.certificateCollector != nil},
+		{"package-updates", smc.packageCollector != nil},
+	} {
+		if collector.enabled {
+			names = append(names, collector.name)
+		}
+	}
+
+	return names
+}
+
+// Collect server metrics once, for a collector which wasn't started
+func (smc *ServerMetricCollector) Collect() (*metrics.ServerMetric, error) {
+	return smc.fetchServerMetrics()
+}
+
+// Close releases connections of a collector which wasn't started
+func (smc *ServerMetricCollector) Close() {
+	if smc.serviceCollector != nil {
+		smc.serviceCollector.Close()
+	}
+
+	for _, runtime := range smc.runtimes {
+		runtime.Close()
+	}
+}
+
+// SchedulerMonitor returns the Laravel scheduler monitor, nil when disabled
+func (smc *ServerMetricCollector) SchedulerMonitor() *SchedulerMonitor {
+	return smc.schedulerMonitor
 }
 
 func (smc *ServerMetricCollector) fetchServerMetrics() (*metrics.ServerMetric, error) {
@@ -103,6 +310,7 @@ func (smc *ServerMetricCollector) fetchServerMetrics() (*metrics.ServerMetric, e
 	if err == nil {
 		metric.MemoryTotal = m.Total
 		metric.MemoryUserPercentage = m.UsedPercent
+		metric.Memory = smc.memoryBreakdown(m)
 	}
 
 	l, err := smc.load()
@@ -110,30 +318,71 @@ func (smc *ServerMetricCollector) fetchServerMetrics() (*metrics.ServerMetric, e
 		metric.Load = *l
 	}
 
+	p, err := readPressure(hostProc("pressure"), smc.lastPressure)
+	if err == nil {
+		metric.Pressure = p
+	} else {
+		log.Trace().Err(err).Msg("Failed to read pressure stall information")
+	}
+
 	d, err := smc.disk()
 	if err == nil {
 		metric.DiskTotal = d.Total
 		metric.DiskUsedPercentage = d.UsedPercent
 	}
 
-	if !smc.inDocker {
-		s, err := Services()
+	if smc.serviceCollector != nil {
+		s, err := smc.serviceCollector.Collect()
 		if err == nil {
 			metric.Services = s
+			metric.ServiceEvents = smc.serviceCollector.Events()
 		} else {
 			log.Trace().Err(err).Msg("Failed to fetch services")
 		}
+	}
+
+	for _, runtime := range smc.runtimes {
+		c, err := runtime.FetchContainers()
+		if err != nil {
+			log.Trace().Err(err).Msgf("Failed to fetch %s containers", runtime.Name())
+			continue
+		}
+		metric.Containers = append(metric.Containers, c...)
 
-		if smc.dockerClient != nil {
-			c, err := smc.dockerClient.FetchContainers()
-			if err != nil {
-				log.Trace().Err(err).Msg("Failed to fetch containers")
+		// compose labels are set by docker-compose, which also drives Podman's Docker API
+		if dockerClient, ok := runtime.(*DockerClient); ok {
+			p, err := dockerClient.FetchComposeProjects(c)
+			if err == nil {
+				metric.ComposeProjects = append(metric.ComposeProjects, p...)
 			} else {
-				metric.Containers = c
+				log.Trace().Err(err).Msg("Failed to fetch compose projects")
 			}
 		}
 	}
 
+	if smc.kubeletClient != nil {
+		if err := smc.kubeletClient.Tag(metric.Containers); err != nil {
+			log.Trace().Err(err).Msg("Failed to fetch Kubernetes pods")
+		}
+	}
+
+	if smc.processCollector != nil {
+		p, err := smc.processCollector.Collect()
+		if err == nil {
+			metric.Processes = p
+		} else {
+			log.Trace().Err(err).Msg("Failed to fetch processes")
+		}
+	}
+
+	if smc.schedulerMonitor != nil {
+		metric.Scheduler = smc.schedulerMonitor.Collect()
+	}
+
+	if smc.certificateCollector != nil {
+		metric.Certificates = smc.certificateCollector.Collect()
+	}
+
 	osInfo, err := smc.os()
 	if err == nil {
 		metric.OS = osInfo
@@ -141,13 +390,67 @@ func (smc *ServerMetricCollector) fetchServerMetrics() (*metrics.ServerMetric, e
 		log.Trace().Err(err)
 	}
 
-	phpVersion, err := smc.phpVersion()
+	phpVersion, err := PHPVersion()
 	if err == nil {
 		metric.PHPVersion = phpVersion
 	} else {
 		log.Trace().Err(err).Msg("Failed to fetch PHP version")
 	}
 
+	if smc.phpFPMCollector != nil {
+		pools, err := smc.phpFPMCollector.Collect()
+		if err == nil {
+			metric.PHPFPMPools = pools
+		} else {
+			log.Trace().Err(err).Msg("Failed to fetch PHP-FPM pools")
+		}
+	}
+
+	if smc.webServerCollector != nil {
+		servers, err := smc.webServerCollector.Collect()
+		if err == nil {
+			metric.WebServers = servers
+		} else {
+			log.Trace().Err(err).Msg("Failed to fetch web server status")
+		}
+	}
+
+	if smc.mysqlCollector != nil {
+		m, err := smc.mysqlCollector.Collect()
+		if err == nil {
+			metric.MySQL = m
+		} else {
+			log.Trace().Err(err).Msg("Failed to fetch MySQL status")
+		}
+	}
+
+	if smc.redisCollector != nil {
+		r, err := smc.redisCollector.Collect()
+		if err == nil {
+			metric.Redis = r
+		} else {
+			log.Trace().Err(err).Msg("Failed to fetch Redis stats")
+		}
+
+		if len(smc.redisCollector.config.HorizonPrefix) > 0 {
+			h, err := smc.redisCollector.Horizon()
+			if err == nil {
+				metric.Horizon = h
+			} else {
+				log.Trace().Err(err).Msg("Failed to fetch Horizon state")
+			}
+		}
+	}
+
+	if smc.queueWorkerCollector != nil {
+		w, err := smc.queueWorkerCollector.Collect()
+		if err == nil {
+			metric.QueueWorkers = w
+		} else {
+			log.Trace().Err(err).Msg("Failed to fetch queue workers")
+		}
+	}
+
 	uptime, err := host.BootTime()
 	if err == nil {
 		metric.BootTime = uptime
@@ -157,6 +460,18 @@ func (smc *ServerMetricCollector) fetchServerMetrics() (*metrics.ServerMetric, e
 		metric.RebootRequired = true
 	}
 
+	if smc.packageCollector != nil {
+		p, err := smc.packageCollector.Collect()
+		if err == nil {
+			metric.PackageUpdates = p
+			if p != nil && len(p.RestartRequired) > 0 {
+				metric.RebootRequired = true
+			}
+		} else {
+			log.Trace().Err(err).Msg("Failed to fetch package updates")
+		}
+	}
+
 	err = nil
 
 	metric.CreatedAt = time.Now()
@@ -191,6 +506,22 @@ func (smc *ServerMetricCollector) memory() (*mem.VirtualMemoryStat, error) {
 	return m, nil
 }
 
+func (smc *ServerMetricCollector) memoryBreakdown(m *mem.VirtualMemoryStat) *metrics.ServerMemory {
+	current, err := readVMStat(hostProc("vmstat"))
+	if err != nil {
+		log.Trace().Err(err).Msg("Failed to read vmstat")
+	}
+
+	memory := memoryBreakdown(m, current, smc.lastVMStat)
+	if memory.OOMKills > 0 {
+		log.Warn().Uint64("oom_kills", memory.OOMKills).Msg("OOM killer invoked since last collection")
+	}
+
+	smc.lastVMStat = current
+
+	return memory
+}
+
 func (smc *ServerMetricCollector) disk() (*disk.UsageStat, error) {
 	m, err := disk.Usage("/")
 	if err != nil {
@@ -227,7 +558,8 @@ func (smc *ServerMetricCollector) os() (*metrics.OS, error) {
 	return osInfo, nil
 }
 
-func (smc *ServerMetricCollector) phpVersion() (string, error) {
+// PHPVersion returns the version of the `php` binary in PATH
+func PHPVersion() (string, error) {
 	phpPath, err := exec.LookPath("php")
 	if err != nil {
 		return "", err
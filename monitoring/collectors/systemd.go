@@ -1,38 +1,378 @@
 package collectors
 
 import (
+	"math"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/coreos/go-systemd/v22/dbus"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 
 	"github.com/larashed/agent-go/monitoring/metrics"
 )
 
-// Services returns systemd services
-func Services() ([]metrics.Service, error) {
-	con, err := dbus.New()
+const (
+	// systemd reports unset numeric properties, e.g. with accounting disabled, as the maximum value
+	systemdUnset = math.MaxUint64
 
-	if err != nil {
-		return nil, err
+	systemdReconnectInterval = 5 * time.Second
+	// events are dropped beyond this limit when collections stall
+	serviceEventLimit = 100
+
+	// a service restarted this many times within the window is in a restart loop
+	restartLoopThreshold = 3
+	restartLoopWindow    = 10 * time.Minute
+)
+
+// systemdConnection is the part of the systemd D-Bus API used by `ServiceCollector`
+type systemdConnection interface {
+	ListUnitsByPatterns(states []string, patterns []string) ([]dbus.UnitStatus, error)
+	GetUnitTypeProperties(unit string, unitType string) (map[string]interface{}, error)
+	GetUnitProperty(unit string, propertyName string) (*dbus.Property, error)
+	Subscribe() error
+	SetPropertiesSubscriber(updateCh chan<- *dbus.PropertiesUpdate, errCh chan<- error)
+	Close()
+}
+
+type serviceState struct {
+	restarts    uint32
+	cpuNSec     uint64
+	collectedAt time.Time
+	restartedAt []time.Time // restarts within `restartLoopWindow`, up to `restartLoopThreshold`
+}
+
+// ServiceCollector keeps a systemd D-Bus connection open, tracks service state changes as they happen
+// and flags restart loops between collections
+type ServiceCollector struct {
+	patterns []string
+	connect  func() (systemdConnection, error)
+
+	con        systemdConnection
+	subscribed bool
+	states     map[string]dbus.UnitStatus
+	previous   map[string]serviceState
+	events     []metrics.ServiceEvent
+	mutex      sync.Mutex
+
+	updates chan *dbus.PropertiesUpdate
+	errs    chan error
+	changes chan struct{}
+	stop    chan struct{}
+}
+
+// NewServiceCollector creates a new instance of `ServiceCollector`.
+// An empty watchlist collects all services, entries may contain globs.
+func NewServiceCollector(watchlist []string) *ServiceCollector {
+	patterns := []string{"*.service"}
+	if len(watchlist) > 0 {
+		patterns = make([]string, 0, len(watchlist))
+		for _, unit := range watchlist {
+			if !strings.HasSuffix(unit, ".service") {
+				unit += ".service"
+			}
+			patterns = append(patterns, unit)
+		}
 	}
 
-	defer con.Close()
+	return &ServiceCollector{
+		patterns: patterns,
+		connect: func() (systemdConnection, error) {
+			return dbus.New()
+		},
+		states:   make(map[string]dbus.UnitStatus),
+		previous: make(map[string]serviceState),
+		updates:  make(chan *dbus.PropertiesUpdate, 256),
+		errs:     make(chan error, 16),
+		changes:  make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+}
 
-	services, err := con.ListUnitsByPatterns([]string{"running"}, []string{"*.service"})
-	if err != nil {
+// Start listening for unit changes, reconnecting when the connection is lost
+func (c *ServiceCollector) Start() {
+	ticker := time.NewTicker(systemdReconnectInterval)
+	defer ticker.Stop()
+
+	c.subscribe()
+
+	for {
+		select {
+		case <-c.stop:
+			c.mutex.Lock()
+			c.disconnect()
+			c.mutex.Unlock()
+
+			return
+		case update := <-c.updates:
+			c.apply(update, time.Now())
+		case err := <-c.errs:
+			// a full update channel means transitions were missed, the next collection catches up
+			log.Trace().Err(err).Msg("systemd subscription error")
+		case <-ticker.C:
+			c.subscribe()
+		}
+	}
+}
+
+// Stop listening for unit changes
+func (c *ServiceCollector) Stop() {
+	c.stop <- struct{}{}
+}
+
+// Close the D-Bus connection of a collector which wasn't started
+func (c *ServiceCollector) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.disconnect()
+}
+
+// Changes signals service failures as soon as they are seen
+func (c *ServiceCollector) Changes() <-chan struct{} {
+	return c.changes
+}
+
+// Events returns and clears state transitions seen since the previous call
+func (c *ServiceCollector) Events() []metrics.ServiceEvent {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	events := c.events
+	c.events = nil
+
+	return events
+}
+
+// Collect returns systemd services in any state
+func (c *ServiceCollector) Collect() ([]metrics.Service, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.connectLocked(); err != nil {
 		return nil, err
 	}
 
+	units, err := c.con.ListUnitsByPatterns(nil, c.patterns)
+	if err != nil {
+		c.disconnect()
+
+		return nil, errors.Wrap(err, "Failed to list systemd units")
+	}
+
+	now := time.Now()
+	current := make(map[string]serviceState)
 	srvs := make([]metrics.Service, 0)
-	for i := 0; i < len(services); i++ {
-		srvs = append(srvs, metrics.Service{
-			Name:        strings.Replace(services[i].Name, ".service", "", 1),
-			Description: services[i].Description,
-			LoadState:   services[i].LoadState,
-			ActiveState: services[i].ActiveState,
-			SubState:    services[i].SubState,
-		})
+	for i := 0; i < len(units); i++ {
+		// catches transitions missed while disconnected or with a full update channel
+		c.transition(units[i].Name, units[i].ActiveState, units[i].SubState, now)
+		c.states[units[i].Name] = units[i]
+
+		service := metrics.Service{
+			Name:        strings.Replace(units[i].Name, ".service", "", 1),
+			Description: units[i].Description,
+			LoadState:   units[i].LoadState,
+			ActiveState: units[i].ActiveState,
+			SubState:    units[i].SubState,
+		}
+
+		// stopped units have no process to measure
+		if units[i].ActiveState != "inactive" {
+			state := c.properties(units[i].Name, &service, now)
+			current[units[i].Name] = state
+		}
+
+		srvs = append(srvs, service)
 	}
 
+	c.previous = current
+
 	return srvs, nil
 }
+
+// subscribe connects when needed and subscribes to unit property changes
+func (c *ServiceCollector) subscribe() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.subscribed {
+		return
+	}
+
+	if err := c.connectLocked(); err != nil {
+		log.Trace().Err(err).Msg("Failed to connect to systemd")
+		return
+	}
+
+	if err := c.con.Subscribe(); err != nil {
+		log.Trace().Err(err).Msg("Failed to subscribe to systemd unit changes")
+		c.disconnect()
+
+		return
+	}
+
+	c.con.SetPropertiesSubscriber(c.updates, c.errs)
+	c.subscribed = true
+}
+
+func (c *ServiceCollector) connectLocked() error {
+	if c.con != nil {
+		return nil
+	}
+
+	con, err := c.connect()
+	if err != nil {
+		return errors.Wrap(err, "Failed to connect to systemd")
+	}
+	c.con = con
+
+	return nil
+}
+
+func (c *ServiceCollector) disconnect() {
+	if c.con == nil {
+		return
+	}
+
+	c.con.Close()
+	c.con = nil
+	c.subscribed = false
+}
+
+// apply records a transition reported by a PropertiesChanged signal
+func (c *ServiceCollector) apply(update *dbus.PropertiesUpdate, now time.Time) {
+	if !c.watched(update.UnitName) {
+		return
+	}
+
+	activeState, hasActive := update.Changed["ActiveState"]
+	subState, hasSub := update.Changed["SubState"]
+	if !hasActive && !hasSub {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	status := c.states[update.UnitName]
+	status.Name = update.UnitName
+	if hasActive {
+		status.ActiveState, _ = activeState.Value().(string)
+	}
+	if hasSub {
+		status.SubState, _ = subState.Value().(string)
+	}
+
+	// systemd sends the transition's own timestamp along with the state
+	at := now
+	property := "InactiveEnterTimestamp"
+	if status.ActiveState == "active" {
+		property = "ActiveEnterTimestamp"
+	}
+	if usec, ok := update.Changed[property].Value().(uint64); ok && usec > 0 {
+		at = time.Unix(0, int64(usec)*int64(time.Microsecond))
+	}
+
+	c.transition(status.Name, status.ActiveState, status.SubState, at)
+	c.states[status.Name] = status
+}
+
+// transition records an event when a unit's active state changes, it expects the mutex to be held
+func (c *ServiceCollector) transition(unit, activeState, subState string, at time.Time) {
+	previous, known := c.states[unit]
+	if !known || previous.ActiveState == activeState {
+		return
+	}
+
+	var eventType string
+	switch activeState {
+	case "active":
+		eventType = metrics.ServiceEventStarted
+	case "inactive":
+		eventType = metrics.ServiceEventStopped
+	case "failed":
+		eventType = metrics.ServiceEventFailed
+	default:
+		return
+	}
+
+	if len(c.events) >= serviceEventLimit {
+		c.events = c.events[1:]
+	}
+	c.events = append(c.events, metrics.ServiceEvent{
+		Name:          strings.Replace(unit, ".service", "", 1),
+		Type:          eventType,
+		PreviousState: previous.ActiveState,
+		SubState:      subState,
+		At:            metrics.FormatTime(at),
+	})
+
+	if eventType == metrics.ServiceEventFailed {
+		select {
+		case c.changes <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *ServiceCollector) watched(unit string) bool {
+	for _, pattern := range c.patterns {
+		if ok, _ := filepath.Match(pattern, unit); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *ServiceCollector) properties(unit string, service *metrics.Service, now time.Time) serviceState {
+	state := serviceState{collectedAt: now}
+
+	props, err := c.con.GetUnitTypeProperties(unit, "Service")
+	if err == nil {
+		state.restarts, _ = props["NRestarts"].(uint32)
+		service.Restarts = state.restarts
+		service.MainPID, _ = props["MainPID"].(uint32)
+		service.Result, _ = props["Result"].(string)
+
+		if memory, ok := props["MemoryCurrent"].(uint64); ok && memory != systemdUnset {
+			service.Memory = memory
+		}
+		if cpu, ok := props["CPUUsageNSec"].(uint64); ok && cpu != systemdUnset {
+			state.cpuNSec = cpu
+		}
+	}
+
+	if prop, err := c.con.GetUnitProperty(unit, "ActiveEnterTimestamp"); err == nil {
+		if usec, ok := prop.Value.Value().(uint64); ok && usec > 0 {
+			service.ActiveEnteredAt = metrics.FormatTime(time.Unix(0, int64(usec)*int64(time.Microsecond)))
+		}
+	}
+
+	previous, ok := c.previous[unit]
+	if ok && state.restarts >= previous.restarts {
+		service.RestartsSinceLastCollection = state.restarts - previous.restarts
+	}
+	if ok && state.cpuNSec >= previous.cpuNSec {
+		elapsed := now.Sub(previous.collectedAt)
+		if elapsed > 0 {
+			service.CPUPercentage = float64(state.cpuNSec-previous.cpuNSec) / float64(elapsed) * 100
+		}
+	}
+
+	if ok {
+		for _, restartedAt := range previous.restartedAt {
+			if now.Sub(restartedAt) < restartLoopWindow {
+				state.restartedAt = append(state.restartedAt, restartedAt)
+			}
+		}
+	}
+	for i := uint32(0); i < service.RestartsSinceLastCollection && len(state.restartedAt) < restartLoopThreshold; i++ {
+		state.restartedAt = append(state.restartedAt, now)
+	}
+	service.RestartLoop = len(state.restartedAt) >= restartLoopThreshold
+
+	return state
+}
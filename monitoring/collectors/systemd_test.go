@@ -0,0 +1,186 @@
+package collectors
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/dbus"
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+type systemdStandIn struct {
+	patterns   []string
+	units      []dbus.UnitStatus
+	props      map[string]map[string]interface{}
+	listErr    error
+	subscribed bool
+	closed     bool
+}
+
+func (s *systemdStandIn) ListUnitsByPatterns(states []string, patterns []string) ([]dbus.UnitStatus, error) {
+	s.patterns = patterns
+	return s.units, s.listErr
+}
+
+func (s *systemdStandIn) GetUnitTypeProperties(unit string, unitType string) (map[string]interface{}, error) {
+	return s.props[unit], nil
+}
+
+func (s *systemdStandIn) GetUnitProperty(unit string, propertyName string) (*dbus.Property, error) {
+	return &dbus.Property{Name: propertyName, Value: godbus.MakeVariant(uint64(1600000000000000))}, nil
+}
+
+func (s *systemdStandIn) Subscribe() error {
+	s.subscribed = true
+	return nil
+}
+
+func (s *systemdStandIn) SetPropertiesSubscriber(updateCh chan<- *dbus.PropertiesUpdate, errCh chan<- error) {
+}
+
+func (s *systemdStandIn) Close() {
+	s.closed = true
+}
+
+func TestServiceCollector(t *testing.T) {
+	standIn := &systemdStandIn{
+		units: []dbus.UnitStatus{
+			{Name: "php7.4-fpm.service", LoadState: "loaded", ActiveState: "activating", SubState: "auto-restart"},
+			{Name: "nginx.service", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+			{Name: "supervisor.service", LoadState: "loaded", ActiveState: "failed", SubState: "failed"},
+			{Name: "apt-daily.service", LoadState: "loaded", ActiveState: "inactive", SubState: "dead"},
+		},
+		props: map[string]map[string]interface{}{
+			"php7.4-fpm.service": {"NRestarts": uint32(3), "MainPID": uint32(0), "Result": "exit-code", "MemoryCurrent": uint64(systemdUnset)},
+			"nginx.service":      {"NRestarts": uint32(0), "MainPID": uint32(42), "Result": "success", "MemoryCurrent": uint64(1024), "CPUUsageNSec": uint64(1000)},
+			"supervisor.service": {"NRestarts": uint32(1), "Result": "exit-code"},
+		},
+	}
+
+	collector := NewServiceCollector([]string{"php*-fpm", "nginx.service", "supervisor", "apt-daily"})
+	collector.connect = func() (systemdConnection, error) {
+		return standIn, nil
+	}
+
+	services, err := collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"php*-fpm.service", "nginx.service", "supervisor.service", "apt-daily.service"}, standIn.patterns)
+	assert.Len(t, services, 4)
+
+	assert.Equal(t, "php7.4-fpm", services[0].Name)
+	assert.Equal(t, uint32(3), services[0].Restarts)
+	assert.Equal(t, uint64(0), services[0].Memory)
+	// restarts before the first collection aren't known to be recent
+	assert.False(t, services[0].RestartLoop)
+
+	assert.Equal(t, uint32(42), services[1].MainPID)
+	assert.Equal(t, uint64(1024), services[1].Memory)
+	assert.Equal(t, metrics.FormatTime(time.Unix(1600000000, 0)), services[1].ActiveEnteredAt)
+	assert.False(t, services[1].RestartLoop)
+
+	assert.Equal(t, "failed", services[2].ActiveState)
+	assert.Equal(t, "exit-code", services[2].Result)
+	assert.False(t, services[2].RestartLoop)
+
+	assert.Equal(t, "", services[3].ActiveEnteredAt)
+
+	// supervisor crashed and was restarted between collections
+	standIn.units[2].ActiveState, standIn.units[2].SubState = "active", "running"
+	standIn.props["supervisor.service"]["NRestarts"] = uint32(3)
+	standIn.props["nginx.service"]["CPUUsageNSec"] = uint64(2000)
+
+	services, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), services[2].RestartsSinceLastCollection)
+	assert.False(t, services[2].RestartLoop)
+	assert.True(t, services[1].CPUPercentage > 0)
+
+	// a third restart within the window is a loop
+	standIn.props["supervisor.service"]["NRestarts"] = uint32(4)
+
+	services, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), services[2].RestartsSinceLastCollection)
+	assert.True(t, services[2].RestartLoop)
+}
+
+func TestServiceCollectorTransitions(t *testing.T) {
+	connections := 0
+	standIn := &systemdStandIn{
+		units: []dbus.UnitStatus{
+			{Name: "php7.4-fpm.service", LoadState: "loaded", ActiveState: "active", SubState: "running"},
+		},
+	}
+
+	collector := NewServiceCollector(nil)
+	collector.connect = func() (systemdConnection, error) {
+		connections++
+		return standIn, nil
+	}
+
+	collector.subscribe()
+	assert.True(t, standIn.subscribed)
+
+	_, err := collector.Collect()
+	assert.NoError(t, err)
+	assert.Empty(t, collector.Events())
+
+	failedAt := time.Now().Add(-time.Second).Truncate(time.Second)
+	collector.apply(&dbus.PropertiesUpdate{
+		UnitName: "php7.4-fpm.service",
+		Changed: map[string]godbus.Variant{
+			"ActiveState":            godbus.MakeVariant("failed"),
+			"SubState":               godbus.MakeVariant("failed"),
+			"InactiveEnterTimestamp": godbus.MakeVariant(uint64(failedAt.UnixNano() / 1000)),
+		},
+	}, time.Now())
+
+	// units outside the watchlist and non-state changes are ignored
+	collector.apply(&dbus.PropertiesUpdate{
+		UnitName: "home.mount",
+		Changed:  map[string]godbus.Variant{"ActiveState": godbus.MakeVariant("inactive")},
+	}, time.Now())
+	collector.apply(&dbus.PropertiesUpdate{
+		UnitName: "php7.4-fpm.service",
+		Changed:  map[string]godbus.Variant{"MemoryCurrent": godbus.MakeVariant(uint64(1))},
+	}, time.Now())
+
+	select {
+	case <-collector.Changes():
+	default:
+		t.Fatal("expected a change notification for the failed service")
+	}
+
+	// the snapshot agrees with the signal, so no duplicate event is recorded
+	standIn.units[0].ActiveState, standIn.units[0].SubState = "failed", "failed"
+	_, err = collector.Collect()
+	assert.NoError(t, err)
+
+	events := collector.Events()
+	assert.Len(t, events, 1)
+	assert.Equal(t, "php7.4-fpm", events[0].Name)
+	assert.Equal(t, "failed", events[0].Type)
+	assert.Equal(t, "active", events[0].PreviousState)
+	assert.Equal(t, metrics.FormatTime(failedAt), events[0].At)
+
+	// a lost connection is closed and re-established on the next attempt
+	standIn.listErr = assert.AnError
+	_, err = collector.Collect()
+	assert.Error(t, err)
+	assert.True(t, standIn.closed)
+
+	standIn.listErr = nil
+	standIn.units[0].ActiveState, standIn.units[0].SubState = "active", "running"
+	collector.subscribe()
+	_, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, connections)
+
+	// transitions missed while disconnected are picked up by the snapshot
+	events = collector.Events()
+	assert.Len(t, events, 1)
+	assert.Equal(t, "started", events[0].Type)
+}
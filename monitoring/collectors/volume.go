@@ -0,0 +1,189 @@
+package collectors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// check the deadline every this many files, a time.Now call per file adds up on large volumes
+const volumeDeadlineCheckEvery = 1000
+
+var errVolumeTimeout = errors.New("volume size calculation timed out")
+
+type volumeSize struct {
+	size         int64
+	errors       int
+	partial      bool
+	calculatedAt time.Time
+	requestedAt  time.Time
+}
+
+type inode struct {
+	dev uint64
+	ino uint64
+}
+
+// VolumeSizer calculates volume disk usage in the background, collections read cached sizes
+type VolumeSizer struct {
+	interval time.Duration
+	timeout  time.Duration
+
+	sizes map[string]*volumeSize
+	mutex sync.Mutex
+
+	wake chan struct{}
+	ctx  context.Context
+	stop context.CancelFunc
+}
+
+// NewVolumeSizer creates a new instance of `VolumeSizer`.
+// Volumes are measured every `interval`, each measurement is cut off after `timeout`.
+func NewVolumeSizer(interval, timeout time.Duration) *VolumeSizer {
+	ctx, stop := context.WithCancel(context.Background())
+
+	return &VolumeSizer{
+		interval: interval,
+		timeout:  timeout,
+		sizes:    make(map[string]*volumeSize),
+		wake:     make(chan struct{}, 1),
+		ctx:      ctx,
+		stop:     stop,
+	}
+}
+
+// Start measuring requested volumes until stopped
+func (vs *VolumeSizer) Start() {
+	ticker := time.NewTicker(vs.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-vs.ctx.Done():
+			return
+		case <-vs.wake:
+			vs.refresh(false)
+		case <-ticker.C:
+			vs.refresh(true)
+		}
+	}
+}
+
+// Stop measuring volumes
+func (vs *VolumeSizer) Stop() {
+	vs.stop()
+}
+
+// Fill sets the cached size of the volume, scheduling a measurement of volumes not seen before
+func (vs *VolumeSizer) Fill(volume *metrics.Volume) {
+	vs.mutex.Lock()
+	defer vs.mutex.Unlock()
+
+	cached, ok := vs.sizes[volume.Source]
+	if !ok {
+		cached = &volumeSize{}
+		vs.sizes[volume.Source] = cached
+
+		select {
+		case vs.wake <- struct{}{}:
+		default:
+		}
+	}
+	cached.requestedAt = time.Now()
+
+	volume.Size = cached.size
+	volume.SizeErrors = cached.errors
+	volume.SizePartial = cached.partial
+	volume.SizeCalculatedAt = metrics.FormatTime(cached.calculatedAt)
+}
+
+// refresh measures volumes which were never measured, or all of them when `all` is set.
+// Volumes no longer requested since the previous round are forgotten.
+// A measurement that times out doesn't replace an earlier complete one.
+func (vs *VolumeSizer) refresh(all bool) {
+	now := time.Now()
+
+	vs.mutex.Lock()
+	var paths []string
+	for path, cached := range vs.sizes {
+		if all && now.Sub(cached.requestedAt) > vs.interval {
+			delete(vs.sizes, path)
+			continue
+		}
+
+		if all || cached.calculatedAt.IsZero() {
+			paths = append(paths, path)
+		}
+	}
+	vs.mutex.Unlock()
+
+	for _, path := range paths {
+		if vs.ctx.Err() != nil {
+			return
+		}
+
+		size, errorCount, partial := measureVolume(path, time.Now().Add(vs.timeout))
+
+		vs.mutex.Lock()
+		cached, ok := vs.sizes[path]
+		// every measurement starts over, so keep the last complete size rather than a smaller partial one
+		if ok && partial && !cached.calculatedAt.IsZero() && !cached.partial {
+			ok = false
+		}
+		if ok {
+			cached.size = size
+			cached.errors = errorCount
+			cached.partial = partial
+			cached.calculatedAt = time.Now()
+		}
+		vs.mutex.Unlock()
+	}
+}
+
+// measureVolume sums allocated blocks like `du`, counting hardlinked files once.
+// Unreadable entries are counted as errors and skipped.
+func measureVolume(path string, deadline time.Time) (size int64, errorCount int, partial bool) {
+	path = strings.Replace(path, "/host_mnt", "", 1)
+	seen := make(map[inode]bool)
+	visited := 0
+
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			errorCount++
+			return nil
+		}
+
+		visited++
+		if visited%volumeDeadlineCheckEvery == 0 && time.Now().After(deadline) {
+			return errVolumeTimeout
+		}
+
+		stat, ok := info.Sys().(*syscall.Stat_t)
+		if !ok {
+			size += info.Size()
+			return nil
+		}
+
+		if !info.IsDir() && stat.Nlink > 1 {
+			key := inode{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}
+			if seen[key] {
+				return nil
+			}
+			seen[key] = true
+		}
+
+		size += int64(stat.Blocks) * 512
+
+		return nil
+	})
+
+	return size, errorCount, err == errVolumeTimeout
+}
@@ -0,0 +1,89 @@
+package collectors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+func TestMeasureVolume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "volume")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "data"), make([]byte, 64*1024), 0644))
+	size, errorCount, partial := measureVolume(dir, time.Now().Add(time.Minute))
+	assert.Equal(t, 0, errorCount)
+	assert.False(t, partial)
+	assert.True(t, size >= 64*1024)
+
+	// a hardlink shares its blocks with the original file
+	assert.NoError(t, os.Link(filepath.Join(dir, "data"), filepath.Join(dir, "link")))
+	linked, _, _ := measureVolume(dir, time.Now().Add(time.Minute))
+	assert.Equal(t, size, linked)
+
+	for i := 0; i < volumeDeadlineCheckEvery; i++ {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d", i)), []byte("x"), 0644))
+	}
+	_, _, partial = measureVolume(dir, time.Now().Add(-time.Second))
+	assert.True(t, partial)
+
+	_, errorCount, _ = measureVolume(filepath.Join(dir, "missing"), time.Now().Add(time.Minute))
+	assert.Equal(t, 1, errorCount)
+}
+
+func TestVolumeSizer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "volume")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "data"), make([]byte, 8*1024), 0644))
+
+	sizer := NewVolumeSizer(time.Hour, time.Minute)
+	go sizer.Start()
+	defer sizer.Stop()
+
+	// unknown volumes are measured in the background, the first collection reports nothing yet
+	volume := metrics.Volume{Source: dir}
+	sizer.Fill(&volume)
+	assert.Equal(t, int64(0), volume.Size)
+	assert.Empty(t, volume.SizeCalculatedAt)
+
+	assert.Eventually(t, func() bool {
+		volume := metrics.Volume{Source: dir}
+		sizer.Fill(&volume)
+		return volume.Size >= 8*1024 && len(volume.SizeCalculatedAt) > 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestVolumeSizerKeepsCompleteSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "volume")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "data"), make([]byte, 64*1024), 0644))
+
+	sizer := NewVolumeSizer(time.Hour, time.Minute)
+	volume := metrics.Volume{Source: dir}
+	sizer.Fill(&volume)
+	sizer.refresh(false)
+	sizer.Fill(&volume)
+	assert.False(t, volume.SizePartial)
+	complete := volume.Size
+
+	// a re-measurement which times out keeps the complete size
+	assert.NoError(t, os.Remove(filepath.Join(dir, "data")))
+	for i := 0; i < volumeDeadlineCheckEvery; i++ {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d", i)), []byte("x"), 0644))
+	}
+	sizer.timeout = -time.Second
+	sizer.refresh(true)
+	sizer.Fill(&volume)
+	assert.False(t, volume.SizePartial)
+	assert.Equal(t, complete, volume.Size)
+}
@@ -0,0 +1,208 @@
+package collectors
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+const (
+	webServerNginx  = "nginx"
+	webServerApache = "apache"
+)
+
+type webServerEndpoint struct {
+	serverType string
+	url        string
+}
+
+type requestCount struct {
+	total       uint64
+	collectedAt time.Time
+}
+
+// WebServerCollector reads nginx `stub_status` and Apache `mod_status?auto` pages
+type WebServerCollector struct {
+	endpoints []webServerEndpoint
+	client    http.Client
+	previous  map[string]requestCount
+}
+
+// NewWebServerCollector creates a new instance of `WebServerCollector`
+func NewWebServerCollector(nginxURLs, apacheURLs []string) *WebServerCollector {
+	endpoints := make([]webServerEndpoint, 0, len(nginxURLs)+len(apacheURLs))
+	for _, url := range nginxURLs {
+		endpoints = append(endpoints, webServerEndpoint{webServerNginx, url})
+	}
+	for _, url := range apacheURLs {
+		endpoints = append(endpoints, webServerEndpoint{webServerApache, url})
+	}
+
+	return &WebServerCollector{
+		endpoints: endpoints,
+		client: http.Client{
+			Timeout: 2 * time.Second,
+		},
+		previous: make(map[string]requestCount),
+	}
+}
+
+// Collect returns the status of every reachable web server
+func (c *WebServerCollector) Collect() ([]metrics.WebServer, error) {
+	var lastErr error
+	servers := make([]metrics.WebServer, 0, len(c.endpoints))
+
+	for _, endpoint := range c.endpoints {
+		server, err := c.collect(endpoint)
+		if err != nil {
+			log.Trace().Err(err).Str("url", endpoint.url).Msg("Failed to fetch web server status")
+			lastErr = err
+
+			continue
+		}
+
+		servers = append(servers, *server)
+	}
+
+	if len(servers) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return servers, nil
+}
+
+func (c *WebServerCollector) collect(endpoint webServerEndpoint) (*metrics.WebServer, error) {
+	res, err := c.client.Get(endpoint.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s status page returned %s", endpoint.serverType, res.Status)
+	}
+
+	var server *metrics.WebServer
+	var total uint64
+	if endpoint.serverType == webServerApache {
+		server, total, err = parseApacheStatus(res.Body)
+	} else {
+		server, total, err = parseNginxStatus(res.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	server.Type = endpoint.serverType
+	server.URL = endpoint.url
+
+	now := time.Now()
+	if last, ok := c.previous[endpoint.url]; ok {
+		if seconds := now.Sub(last.collectedAt).Seconds(); seconds > 0 {
+			server.RequestsPerSecond = float64(counterDelta(total, last.total)) / seconds
+		}
+	}
+	c.previous[endpoint.url] = requestCount{total, now}
+
+	return server, nil
+}
+
+// parseNginxStatus parses `stub_status` output:
+//
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func parseNginxStatus(r io.Reader) (*metrics.WebServer, uint64, error) {
+	server := &metrics.WebServer{}
+	var total uint64
+	var parsed bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[0] == "Active" && len(fields) == 3:
+			server.ActiveConnections, _ = strconv.ParseUint(fields[2], 10, 64)
+			parsed = true
+		case fields[0] == "Reading:" && len(fields) == 6:
+			server.Reading, _ = strconv.ParseUint(fields[1], 10, 64)
+			server.Writing, _ = strconv.ParseUint(fields[3], 10, 64)
+			server.Waiting, _ = strconv.ParseUint(fields[5], 10, 64)
+		case len(fields) == 3:
+			if requests, err := strconv.ParseUint(fields[2], 10, 64); err == nil {
+				total = requests
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if !parsed {
+		return nil, 0, errors.New("Unexpected nginx stub_status output")
+	}
+
+	return server, total, nil
+}
+
+// parseApacheStatus parses `mod_status?auto` output. Connection states are
+// taken from the scoreboard: R is reading, W is writing and K is keep-alive.
+func parseApacheStatus(r io.Reader) (*metrics.WebServer, uint64, error) {
+	server := &metrics.WebServer{}
+	var total uint64
+	var parsed bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		value := strings.TrimSpace(kv[1])
+		switch kv[0] {
+		case "Total Accesses":
+			total, _ = strconv.ParseUint(value, 10, 64)
+		case "BusyWorkers":
+			server.BusyWorkers, _ = strconv.ParseUint(value, 10, 64)
+			parsed = true
+		case "IdleWorkers":
+			server.IdleWorkers, _ = strconv.ParseUint(value, 10, 64)
+		case "ConnsTotal":
+			server.ActiveConnections, _ = strconv.ParseUint(value, 10, 64)
+		case "Scoreboard":
+			server.Reading = uint64(strings.Count(value, "R"))
+			server.Writing = uint64(strings.Count(value, "W"))
+			server.Waiting = uint64(strings.Count(value, "K"))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if !parsed {
+		return nil, 0, errors.New("Unexpected Apache mod_status output")
+	}
+
+	// prefork MPM doesn't report ConnsTotal
+	if server.ActiveConnections == 0 {
+		server.ActiveConnections = server.Reading + server.Writing + server.Waiting
+	}
+
+	return server, total, nil
+}
@@ -0,0 +1,47 @@
+package collectors
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebServerCollector(t *testing.T) {
+	requests := 100
+
+	nginx := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Active connections: 291 \nserver accepts handled requests\n 16630948 16630948 %d \nReading: 6 Writing: 179 Waiting: 106 \n", requests)
+	}))
+	defer nginx.Close()
+
+	apache := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Total Accesses: %d\nTotal kBytes: 10\nBusyWorkers: 2\nIdleWorkers: 48\nScoreboard: _RW_K_..\n", requests)
+	}))
+	defer apache.Close()
+
+	collector := NewWebServerCollector([]string{nginx.URL}, []string{apache.URL, "http://127.0.0.1:1/missing"})
+	servers, err := collector.Collect()
+	assert.NoError(t, err)
+	assert.Len(t, servers, 2)
+
+	assert.Equal(t, "nginx", servers[0].Type)
+	assert.Equal(t, uint64(291), servers[0].ActiveConnections)
+	assert.Equal(t, uint64(179), servers[0].Writing)
+	assert.Equal(t, uint64(106), servers[0].Waiting)
+	assert.Equal(t, float64(0), servers[0].RequestsPerSecond)
+
+	assert.Equal(t, "apache", servers[1].Type)
+	assert.Equal(t, uint64(2), servers[1].BusyWorkers)
+	assert.Equal(t, uint64(48), servers[1].IdleWorkers)
+	assert.Equal(t, uint64(1), servers[1].Reading)
+	assert.Equal(t, uint64(3), servers[1].ActiveConnections)
+
+	requests = 200
+	servers, err = collector.Collect()
+	assert.NoError(t, err)
+	assert.True(t, servers[0].RequestsPerSecond > 0)
+	assert.True(t, servers[1].RequestsPerSecond > 0)
+}
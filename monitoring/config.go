@@ -18,4 +18,8 @@ type Config struct {
 	AppMetricSleepDurationOnFailure time.Duration
 	// trigger server metric collection
 	ServerMetricSendInterval time.Duration
+	// forward Laravel log summaries
+	LogReportInterval time.Duration
+	// send queued container events
+	ContainerEventSendInterval time.Duration
 }
@@ -0,0 +1,15 @@
+package metrics
+
+// Certificate represents a TLS certificate served by an endpoint or stored in a file
+type Certificate struct {
+	Source          string   `json:"source"` // host:port or file path
+	Type            string   `json:"type"`   // endpoint or file
+	Subject         string   `json:"subject"`
+	Issuer          string   `json:"issuer"`
+	SANs            []string `json:"sans"`
+	NotBefore       string   `json:"not_before"`
+	NotAfter        string   `json:"not_after"`
+	DaysUntilExpiry int      `json:"days_until_expiry"` // Negative once expired
+	Valid           bool     `json:"valid"`             // Whether the chain verifies against system roots
+	Error           string   `json:"error"`             // Connection, parsing or chain verification error
+}
@@ -8,8 +8,12 @@ type Volume struct {
 	Destination string `json:"destination"`
 	Driver      string `json:"driver"`
 	Mode        string `json:"mode"`
-	Size        int64  `json:"size"`
+	Size        int64  `json:"size"` // Allocated bytes, like du
 	RW          bool   `json:"rw"`
+
+	SizeErrors       int    `json:"size_errors"`        // Entries which couldn't be read while measuring
+	SizePartial      bool   `json:"size_partial"`       // No measurement completed yet, size is a lower bound
+	SizeCalculatedAt string `json:"size_calculated_at"` // Empty until the first measurement finishes
 }
 
 // Port defines a container port
@@ -33,6 +37,10 @@ type Container struct {
 
 	Labels map[string]string `json:"labels"`
 
+	ComposeProject string `json:"compose_project"` // Empty for containers not started by Docker Compose
+	ComposeService string `json:"compose_service"`
+	ComposeNumber  int    `json:"compose_number"`
+
 	Image         string `json:"image"`
 	SizeContainer int64  `json:"size_container"`
 	SizeAdded     int64  `json:"size_added"`
@@ -55,4 +63,53 @@ type Container struct {
 	NetworkOutbound float64 `json:"network_outbound"`
 
 	PIDs uint64 `json:"pid_count"`
+
+	BlockRead  float64 `json:"block_read"`
+	BlockWrite float64 `json:"block_write"`
+
+	RestartCount int  `json:"restart_count"`
+	OOMKilled    bool `json:"oom_killed"`
+	ExitCode     int  `json:"exit_code"`
+
+	Health *ContainerHealth `json:"health"` // Nil when the container has no healthcheck
+
+	Kubernetes *KubernetesPod `json:"kubernetes"` // Nil outside of Kubernetes node mode
+}
+
+// KubernetesPod defines the pod a container belongs to
+type KubernetesPod struct {
+	Namespace  string `json:"namespace"`
+	Pod        string `json:"pod"`
+	PodUID     string `json:"pod_uid"`
+	Container  string `json:"container"`  // Container name within the pod spec
+	OwnerKind  string `json:"owner_kind"` // Controller of the pod, e.g. ReplicaSet, DaemonSet or Job
+	OwnerName  string `json:"owner_name"`
+	Deployment string `json:"deployment"` // Set when the pod's ReplicaSet belongs to a Deployment
+}
+
+// ContainerHealth defines container healthcheck results
+type ContainerHealth struct {
+	Status         string `json:"status"`          // starting, healthy or unhealthy
+	FailingStreak  int    `json:"failing_streak"`  // Consecutive failed checks
+	RecentFailures int    `json:"recent_failures"` // Failed checks among the last few kept by Docker, reveals flapping
+	LastOutput     string `json:"last_output"`
+}
+
+// ComposeService defines a Docker Compose service summary
+type ComposeService struct {
+	Name              string  `json:"name"`
+	DesiredReplicas   int     `json:"desired_replicas"` // Containers created for the service, running or not
+	RunningReplicas   int     `json:"running_replicas"`
+	CPUUsedPercentage float64 `json:"cpu_used_percentage"`
+	MemoryCurrent     float64 `json:"memory_current"`
+}
+
+// ComposeProject defines a Docker Compose project summary
+type ComposeProject struct {
+	Name              string           `json:"name"`
+	Services          []ComposeService `json:"services"`
+	DesiredReplicas   int              `json:"desired_replicas"`
+	RunningReplicas   int              `json:"running_replicas"`
+	CPUUsedPercentage float64          `json:"cpu_used_percentage"`
+	MemoryCurrent     float64          `json:"memory_current"`
 }
@@ -0,0 +1,14 @@
+package metrics
+
+// ContainerEvent represents a container lifecycle event reported by Docker
+type ContainerEvent struct {
+	Hostname     string `json:"hostname"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Image        string `json:"image"`
+	Action       string `json:"action"`        // create, start, die, kill, oom, health_status or restart
+	ExitCode     *int   `json:"exit_code"`     // Set for die events
+	Signal       string `json:"signal"`        // Set for kill events
+	HealthStatus string `json:"health_status"` // Set for health_status events
+	Time         string `json:"time"`
+}
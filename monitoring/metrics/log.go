@@ -0,0 +1,19 @@
+package metrics
+
+// LogEntry represents a parsed Monolog entry
+type LogEntry struct {
+	CreatedAt string `json:"created_at"`
+	Level     string `json:"level"`
+	Channel   string `json:"channel"`
+	Message   string `json:"message"`
+	File      string `json:"file"`
+}
+
+// LogReport summarises Laravel log entries written during a report interval
+type LogReport struct {
+	AppPath   string            `json:"app_path"`
+	StartedAt string            `json:"started_at"`
+	EndedAt   string            `json:"ended_at"`
+	Counts    map[string]uint64 `json:"counts"`  // Entries per level
+	Entries   []LogEntry        `json:"entries"` // Sampled ERROR and more severe entries
+}
@@ -0,0 +1,20 @@
+package metrics
+
+// MySQL represents MySQL/MariaDB server health.
+// Counters are reported as deltas since the previous collection.
+type MySQL struct {
+	Version             string  `json:"version"`
+	Uptime              uint64  `json:"uptime"`
+	MaxConnections      uint64  `json:"max_connections"`
+	MaxUsedConnections  uint64  `json:"max_used_connections"`
+	ThreadsConnected    uint64  `json:"threads_connected"`
+	ThreadsRunning      uint64  `json:"threads_running"`
+	AbortedConnects     uint64  `json:"aborted_connects"`
+	SlowQueries         uint64  `json:"slow_queries"`
+	QueriesPerSecond    float64 `json:"queries_per_second"`
+	BufferPoolHitRate   float64 `json:"buffer_pool_hit_rate"` // Percentage of InnoDB reads served from memory
+	RowLockWaits        uint64  `json:"row_lock_waits"`
+	RowLockCurrentWaits uint64  `json:"row_lock_current_waits"`
+	Replica             bool    `json:"replica"`
+	ReplicationLag      *int64  `json:"replication_lag"` // Seconds behind the source, null when replication is stopped
+}
@@ -0,0 +1,10 @@
+package metrics
+
+// PackageUpdates represents pending operating system package updates
+type PackageUpdates struct {
+	Manager         string   `json:"manager"` // apt, dnf or yum
+	Pending         int      `json:"pending"`
+	Security        int      `json:"security"`
+	RestartRequired []string `json:"restart_required"` // Packages updated since boot which need a reboot
+	CheckedAt       string   `json:"checked_at"`
+}
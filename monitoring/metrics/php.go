@@ -0,0 +1,20 @@
+package metrics
+
+// PHPFPMPool represents a PHP-FPM pool status page.
+// Counters are cumulative since the pool started, see `StartSince`.
+type PHPFPMPool struct {
+	Name                string `json:"name"`
+	Address             string `json:"address"`
+	ProcessManager      string `json:"process_manager"`
+	StartSince          uint64 `json:"start_since"` // Seconds since the pool was (re)started
+	AcceptedConnections uint64 `json:"accepted_connections"`
+	ListenQueue         uint64 `json:"listen_queue"`
+	MaxListenQueue      uint64 `json:"max_listen_queue"`
+	ListenQueueLength   uint64 `json:"listen_queue_length"`
+	IdleProcesses       uint64 `json:"idle_processes"`
+	ActiveProcesses     uint64 `json:"active_processes"`
+	TotalProcesses      uint64 `json:"total_processes"`
+	MaxActiveProcesses  uint64 `json:"max_active_processes"`
+	MaxChildrenReached  uint64 `json:"max_children_reached"`
+	SlowRequests        uint64 `json:"slow_requests"`
+}
@@ -0,0 +1,19 @@
+package metrics
+
+// Process represents a single running process
+type Process struct {
+	PID               int32   `json:"pid"`
+	Name              string  `json:"name"`
+	User              string  `json:"user"`
+	Cmdline           string  `json:"cmdline"`
+	CPUUsedPercentage float64 `json:"cpu_used_percentage"` // 100% equals one fully used core
+	MemoryRSS         uint64  `json:"memory_rss"`
+	Threads           int32   `json:"thread_count"`
+	OpenFiles         int32   `json:"open_fd_count"` // -1 when the agent isn't allowed to inspect descriptors
+}
+
+// ProcessTable holds the processes using the most resources
+type ProcessTable struct {
+	ByCPU    []Process `json:"by_cpu"`
+	ByMemory []Process `json:"by_memory"`
+}
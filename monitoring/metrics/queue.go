@@ -0,0 +1,52 @@
+package metrics
+
+// QueueWorker represents a running `artisan queue:work`, `queue:listen` or Horizon process
+type QueueWorker struct {
+	PID        int32  `json:"pid"`
+	Command    string `json:"command"` // queue:work, queue:listen, horizon, horizon:supervisor or horizon:work
+	Connection string `json:"connection"`
+	Queue      string `json:"queue"`
+	Uptime     uint64 `json:"uptime"` // Seconds since the process started
+	MemoryRSS  uint64 `json:"memory_rss"`
+}
+
+// QueueWorkerGroup summarises workers processing the same connection and queue
+type QueueWorkerGroup struct {
+	Command    string `json:"command"`
+	Connection string `json:"connection"`
+	Queue      string `json:"queue"`
+	Workers    int    `json:"workers"`  // Zero once every worker of a previously seen group is gone
+	Restarts   uint64 `json:"restarts"` // Worker processes started since the previous collection
+}
+
+// QueueWorkers holds queue worker processes observed on the host
+type QueueWorkers struct {
+	Groups  []QueueWorkerGroup `json:"groups"`
+	Workers []QueueWorker      `json:"workers"`
+}
+
+// HorizonMaster represents a Horizon master supervisor
+type HorizonMaster struct {
+	Name   string `json:"name"`
+	PID    string `json:"pid"`
+	Status string `json:"status"` // running, paused or inactive when its heartbeat expired
+}
+
+// HorizonSupervisor represents a Horizon supervisor
+type HorizonSupervisor struct {
+	Name      string         `json:"name"`
+	Master    string         `json:"master"`
+	PID       string         `json:"pid"`
+	Status    string         `json:"status"`    // running, paused or inactive when its heartbeat expired
+	Processes map[string]int `json:"processes"` // Worker processes per "connection:queue"
+}
+
+// Horizon represents Laravel Horizon state read from Redis
+type Horizon struct {
+	Masters       []HorizonMaster     `json:"masters"`
+	Supervisors   []HorizonSupervisor `json:"supervisors"`
+	PendingJobs   uint64              `json:"pending_jobs"`
+	CompletedJobs uint64              `json:"completed_jobs"`
+	RecentJobs    uint64              `json:"recent_jobs"`
+	FailedJobs    uint64              `json:"failed_jobs"`
+}
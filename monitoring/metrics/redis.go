@@ -0,0 +1,29 @@
+package metrics
+
+// RedisQueue represents the backlog of a Laravel Redis queue
+type RedisQueue struct {
+	Name     string `json:"name"`
+	Pending  uint64 `json:"pending"`  // Jobs waiting in the `queues:<name>` list
+	Delayed  uint64 `json:"delayed"`  // Jobs in the `queues:<name>:delayed` sorted set
+	Reserved uint64 `json:"reserved"` // Jobs in the `queues:<name>:reserved` sorted set
+}
+
+// Redis represents Redis `INFO` stats.
+// Evictions, expirations, hits and misses are reported as deltas since the previous collection.
+type Redis struct {
+	Version            string            `json:"version"`
+	Uptime             uint64            `json:"uptime"`
+	UsedMemory         uint64            `json:"used_memory"`
+	UsedMemoryRSS      uint64            `json:"used_memory_rss"`
+	MaxMemory          uint64            `json:"max_memory"`
+	FragmentationRatio float64           `json:"fragmentation_ratio"`
+	ConnectedClients   uint64            `json:"connected_clients"`
+	BlockedClients     uint64            `json:"blocked_clients"`
+	OpsPerSecond       uint64            `json:"ops_per_second"`
+	EvictedKeys        uint64            `json:"evicted_keys"`
+	ExpiredKeys        uint64            `json:"expired_keys"`
+	KeyspaceHits       uint64            `json:"keyspace_hits"`
+	KeyspaceMisses     uint64            `json:"keyspace_misses"`
+	Keyspace           map[string]uint64 `json:"keyspace"` // Key count per database
+	Queues             []RedisQueue      `json:"queues"`
+}
@@ -0,0 +1,23 @@
+package metrics
+
+const (
+	// SchedulerEventMissed is emitted when no `schedule:run` was seen within the configured window
+	SchedulerEventMissed = "missed_schedule"
+	// SchedulerEventResumed is emitted when `schedule:run` is seen again after a miss
+	SchedulerEventResumed = "schedule_resumed"
+)
+
+// SchedulerEvent represents a change in Laravel scheduler health
+type SchedulerEvent struct {
+	Type       string `json:"type"`
+	LastRunAt  string `json:"last_run_at"`
+	DetectedAt string `json:"detected_at"`
+}
+
+// Scheduler represents Laravel scheduler (`artisan schedule:run`) health
+type Scheduler struct {
+	LastRunAt string           `json:"last_run_at"` // Empty when no run was seen since the agent started
+	Source    string           `json:"source"`      // How the last run was observed: process or socket
+	Missed    bool             `json:"missed"`
+	Events    []SchedulerEvent `json:"events"` // Events since the previous collection
+}
@@ -13,6 +13,32 @@ type Service struct {
 	ActiveState string `json:"active_state"` // The active state (i.e. whether the unit is currently started or not)
 	SubState    string `json:"sub_state"`    // The sub state (a more fine-grained version of the active state that is specific to the
 	// unit type, which the active state is not)
+	Result                      string  `json:"result"`                         // The result of the last run, e.g. exit-code
+	Restarts                    uint32  `json:"restarts"`                       // Automatic restarts (NRestarts)
+	RestartsSinceLastCollection uint32  `json:"restarts_since_last_collection"` // Automatic restarts since the previous collection
+	RestartLoop                 bool    `json:"restart_loop"`                   // Restarted at least 3 times within 10 minutes
+	ActiveEnteredAt             string  `json:"active_entered_at"`              // When the unit last entered the active state
+	MainPID                     uint32  `json:"main_pid"`
+	Memory                      uint64  `json:"memory"` // Memory used by the unit's cgroup, in bytes
+	CPUPercentage               float64 `json:"cpu_percentage"`
+}
+
+const (
+	// ServiceEventStarted is emitted when a service becomes active
+	ServiceEventStarted = "started"
+	// ServiceEventStopped is emitted when a service becomes inactive
+	ServiceEventStopped = "stopped"
+	// ServiceEventFailed is emitted when a service enters the failed state
+	ServiceEventFailed = "failed"
+)
+
+// ServiceEvent represents a systemd service state transition
+type ServiceEvent struct {
+	Name          string `json:"name"`
+	Type          string `json:"type"`
+	PreviousState string `json:"previous_state"` // The active state before the transition
+	SubState      string `json:"sub_state"`
+	At            string `json:"at"`
 }
 
 // ServerLoad represents server load metric
@@ -22,6 +48,40 @@ type ServerLoad struct {
 	Load15 float64 `json:"load15"`
 }
 
+// ServerMemory represents a memory breakdown from /proc/meminfo and /proc/vmstat
+type ServerMemory struct {
+	Available   uint64  `json:"available"`
+	Buffers     uint64  `json:"buffers"`
+	Cached      uint64  `json:"cached"`
+	Slab        uint64  `json:"slab"`
+	SwapTotal   uint64  `json:"swap_total"`
+	SwapUsed    uint64  `json:"swap_used"`
+	SwapInRate  float64 `json:"swap_in_rate"`  // Bytes swapped in per second since the previous collection
+	SwapOutRate float64 `json:"swap_out_rate"` // Bytes swapped out per second since the previous collection
+	OOMKills    uint64  `json:"oom_kills"`     // OOM killer invocations since the previous collection
+}
+
+// PressureStall represents a single pressure stall information line
+type PressureStall struct {
+	Avg10  float64 `json:"avg10"`  // Share of time stalled over the last 10 seconds, in percent
+	Avg60  float64 `json:"avg60"`  // Share of time stalled over the last 60 seconds, in percent
+	Avg300 float64 `json:"avg300"` // Share of time stalled over the last 300 seconds, in percent
+	Total  uint64  `json:"total"`  // Microseconds stalled since the previous collection
+}
+
+// PressureResource represents stall information for a single resource
+type PressureResource struct {
+	Some *PressureStall `json:"some"` // At least one task stalled
+	Full *PressureStall `json:"full"` // All non-idle tasks stalled
+}
+
+// ServerPressure represents Linux pressure stall information (PSI)
+type ServerPressure struct {
+	CPU    *PressureResource `json:"cpu"`
+	Memory *PressureResource `json:"memory"`
+	IO     *PressureResource `json:"io"`
+}
+
 // OS represents the underlying OS information
 type OS struct {
 	Name    string `json:"name"`
@@ -30,22 +90,36 @@ type OS struct {
 
 // ServerMetric represents a server metric
 type ServerMetric struct {
-	Hostname             string      `json:"hostname"`
-	CPUUsedPercentage    float64     `json:"cpu_used_percentage"`
-	CPUCoreCount         int         `json:"cpu_core_count"`
-	Load                 ServerLoad  `json:"load"`
-	MemoryTotal          uint64      `json:"memory_total"`
-	MemoryUserPercentage float64     `json:"memory_used_percentage"`
-	DiskTotal            uint64      `json:"disk_total"`
-	DiskUsedPercentage   float64     `json:"disk_used_percentage"`
-	CreatedAt            time.Time   `json:"-"`
-	CreatedAtFormatted   string      `json:"created_at"`
-	OS                   *OS         `json:"os"`
-	BootTime             uint64      `json:"boot_time"`
-	RebootRequired       bool        `json:"reboot_required"`
-	Services             []Service   `json:"services"`
-	Containers           []Container `json:"containers"`
-	PHPVersion           string      `json:"php_version"`
+	Hostname             string           `json:"hostname"`
+	CPUUsedPercentage    float64          `json:"cpu_used_percentage"`
+	CPUCoreCount         int              `json:"cpu_core_count"`
+	Load                 ServerLoad       `json:"load"`
+	Pressure             *ServerPressure  `json:"pressure"`
+	MemoryTotal          uint64           `json:"memory_total"`
+	MemoryUserPercentage float64          `json:"memory_used_percentage"`
+	Memory               *ServerMemory    `json:"memory"`
+	DiskTotal            uint64           `json:"disk_total"`
+	DiskUsedPercentage   float64          `json:"disk_used_percentage"`
+	CreatedAt            time.Time        `json:"-"`
+	CreatedAtFormatted   string           `json:"created_at"`
+	OS                   *OS              `json:"os"`
+	BootTime             uint64           `json:"boot_time"`
+	RebootRequired       bool             `json:"reboot_required"`
+	Services             []Service        `json:"services"`
+	ServiceEvents        []ServiceEvent   `json:"service_events"` // Service state transitions since the previous collection
+	Containers           []Container      `json:"containers"`
+	ComposeProjects      []ComposeProject `json:"compose_projects"`
+	Processes            *ProcessTable    `json:"processes"`
+	PHPVersion           string           `json:"php_version"`
+	PHPFPMPools          []PHPFPMPool     `json:"php_fpm_pools"`
+	WebServers           []WebServer      `json:"web_servers"`
+	MySQL                *MySQL           `json:"mysql"`
+	Redis                *Redis           `json:"redis"`
+	Horizon              *Horizon         `json:"horizon"`
+	QueueWorkers         *QueueWorkers    `json:"queue_workers"`
+	Scheduler            *Scheduler       `json:"scheduler"`
+	Certificates         []Certificate    `json:"certificates"`
+	PackageUpdates       *PackageUpdates  `json:"package_updates"`
 }
 
 // String returns `ServerMetric` in a string format
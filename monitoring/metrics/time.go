@@ -0,0 +1,12 @@
+package metrics
+
+import "time"
+
+// FormatTime formats metric timestamps as RFC3339, zero times as an empty string
+func FormatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
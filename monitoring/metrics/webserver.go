@@ -0,0 +1,14 @@
+package metrics
+
+// WebServer represents nginx `stub_status` or Apache `mod_status` data
+type WebServer struct {
+	Type              string  `json:"type"` // nginx or apache
+	URL               string  `json:"url"`
+	ActiveConnections uint64  `json:"active_connections"`
+	Reading           uint64  `json:"reading"`
+	Writing           uint64  `json:"writing"`
+	Waiting           uint64  `json:"waiting"`
+	RequestsPerSecond float64 `json:"requests_per_second"` // Calculated since the previous collection
+	BusyWorkers       uint64  `json:"busy_workers"`        // Apache only
+	IdleWorkers       uint64  `json:"idle_workers"`        // Apache only
+}
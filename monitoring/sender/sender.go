@@ -3,6 +3,7 @@ package sender
 import (
 	"math"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -10,36 +11,66 @@ import (
 	"github.com/larashed/agent-go/api"
 	"github.com/larashed/agent-go/monitoring"
 	"github.com/larashed/agent-go/monitoring/buckets"
+	"github.com/larashed/agent-go/monitoring/metrics"
+)
+
+// Streams reported by `Status`
+const (
+	StreamAppMetrics      = "app_metrics"
+	StreamServerMetrics   = "server_metrics"
+	StreamContainerEvents = "container_events"
 )
 
 // InternalMetrics ...
 type InternalMetrics struct {
-	AppMetricsReceived uint64
-	AppMetricsSent     uint64
-	APICallsSuccess    uint64
-	APICallsFail       uint64
-	APICallsEmpty      uint64
-	DiscardedItems     uint64
+	AppMetricsReceived uint64 `json:"app_metrics_received"`
+	AppMetricsSent     uint64 `json:"app_metrics_sent"`
+	APICallsSuccess    uint64 `json:"api_calls_success"`
+	APICallsFail       uint64 `json:"api_calls_fail"`
+	APICallsEmpty      uint64 `json:"api_calls_empty"`
+	DiscardedItems     uint64 `json:"discarded_items"`
+}
+
+// StreamStatus holds the outcome of API calls sending a single stream
+type StreamStatus struct {
+	LastSuccessAt string `json:"last_success_at"`
+	LastErrorAt   string `json:"last_error_at"`
+	LastError     string `json:"last_error"`
+	Successes     uint64 `json:"successes"`
+	Failures      uint64 `json:"failures"`
+}
+
+type streamState struct {
+	lastSuccessAt time.Time
+	lastErrorAt   time.Time
+	lastError     string
+	successes     uint64
+	failures      uint64
 }
 
 // Sender defines a metric sender
 type Sender struct {
 	api api.Api
 
-	appMetricBucket    *buckets.AppMetricBucket
-	serverMetricBucket *buckets.ServerMetricBucket
+	appMetricBucket      *buckets.AppMetricBucket
+	serverMetricBucket   *buckets.ServerMetricBucket
+	containerEventBucket *buckets.ContainerEventBucket
 
 	config *monitoring.Config
 
 	sentAt time.Time
 	mutex  sync.RWMutex
 
-	stopServerMetricSend chan int
-	stopAppMetricSend    chan int
+	stopServerMetricSend   chan int
+	stopAppMetricSend      chan int
+	stopContainerEventSend chan int
 
 	appMetricFails int
 
 	internalMetrics *InternalMetrics
+
+	streams     map[string]*streamState
+	streamMutex sync.Mutex
 }
 
 // NewSender creates an instance of `Sender`
@@ -47,19 +78,24 @@ func NewSender(
 	api api.Api,
 	appMetricBucket *buckets.AppMetricBucket,
 	serverMetricBucket *buckets.ServerMetricBucket,
+	containerEventBucket *buckets.ContainerEventBucket,
 	config *monitoring.Config,
 	inspect bool) *Sender {
 	sender := &Sender{
 		api,
 		appMetricBucket,
 		serverMetricBucket,
+		containerEventBucket,
 		config,
 		time.Now(),
 		sync.RWMutex{},
 		make(chan int, 0),
 		make(chan int, 0),
+		make(chan int, 0),
 		0,
 		nil,
+		make(map[string]*streamState),
+		sync.Mutex{},
 	}
 
 	if inspect {
@@ -81,9 +117,75 @@ func (s *Sender) StopSendingServerMetrics() {
 	s.stopServerMetricSend <- 1
 }
 
-// GetInternalMetrics returns internal sender metrics
+// GetInternalMetrics returns a snapshot of internal sender metrics, nil unless inspecting
 func (s *Sender) GetInternalMetrics() *InternalMetrics {
-	return s.internalMetrics
+	if s.internalMetrics == nil {
+		return nil
+	}
+
+	return &InternalMetrics{
+		AppMetricsReceived: atomic.LoadUint64(&s.internalMetrics.AppMetricsReceived),
+		AppMetricsSent:     atomic.LoadUint64(&s.internalMetrics.AppMetricsSent),
+		APICallsSuccess:    atomic.LoadUint64(&s.internalMetrics.APICallsSuccess),
+		APICallsFail:       atomic.LoadUint64(&s.internalMetrics.APICallsFail),
+		APICallsEmpty:      atomic.LoadUint64(&s.internalMetrics.APICallsEmpty),
+		DiscardedItems:     atomic.LoadUint64(&s.internalMetrics.DiscardedItems),
+	}
+}
+
+// Status returns the outcome of API calls per stream
+func (s *Sender) Status() map[string]StreamStatus {
+	s.streamMutex.Lock()
+	defer s.streamMutex.Unlock()
+
+	status := make(map[string]StreamStatus, len(s.streams))
+	for name, stream := range s.streams {
+		status[name] = StreamStatus{
+			LastSuccessAt: metrics.FormatTime(stream.lastSuccessAt),
+			LastErrorAt:   metrics.FormatTime(stream.lastErrorAt),
+			LastError:     stream.lastError,
+			Successes:     stream.successes,
+			Failures:      stream.failures,
+		}
+	}
+
+	return status
+}
+
+// Flush sends queued app metrics and container events right away
+func (s *Sender) Flush() {
+	if count := s.appMetricBucket.Count(); count > 0 {
+		rounds := int(math.Ceil(float64(count) / float64(s.config.AppMetricSendCount)))
+		for i := 0; i < rounds; i++ {
+			s.sendAppMetrics(s.appMetricBucket.Extract(s.config.AppMetricSendCount), "flush")
+		}
+	}
+
+	if s.containerEventBucket.Count() > 0 {
+		s.sendContainerEvents()
+	}
+}
+
+func (s *Sender) record(stream string, err error) {
+	s.streamMutex.Lock()
+	defer s.streamMutex.Unlock()
+
+	state, ok := s.streams[stream]
+	if !ok {
+		state = &streamState{}
+		s.streams[stream] = state
+	}
+
+	if err != nil {
+		state.lastErrorAt = time.Now()
+		state.lastError = err.Error()
+		state.failures++
+
+		return
+	}
+
+	state.lastSuccessAt = time.Now()
+	state.successes++
 }
 
 // StopSendingAppMetrics stops sending app metrics
@@ -104,6 +206,7 @@ func (s *Sender) StartServerMetricSend() {
 					Msgf("Server metrics: %s", metric.String())
 
 				_, err := s.api.SendServerMetrics(metric.String())
+				s.record(StreamServerMetrics, err)
 				if err != nil {
 					log.Err(err).Msg("Failed to send server metrics")
 				}
@@ -114,6 +217,43 @@ func (s *Sender) StartServerMetricSend() {
 	}()
 }
 
+// StopSendingContainerEvents stops sending container events
+func (s *Sender) StopSendingContainerEvents() {
+	s.stopContainerEventSend <- 1
+}
+
+// StartContainerEventSend periodically sends queued container events
+func (s *Sender) StartContainerEventSend() {
+	go func() {
+		ticker := time.NewTicker(s.config.ContainerEventSendInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if s.containerEventBucket.Count() == 0 {
+					continue
+				}
+
+				s.sendContainerEvents()
+			case <-s.stopContainerEventSend:
+				return
+			}
+		}
+	}()
+}
+
+func (s *Sender) sendContainerEvents() {
+	events := s.containerEventBucket.Extract()
+	_, err := s.api.SendContainerEvents(buckets.EventsString(events))
+	s.record(StreamContainerEvents, err)
+	if err != nil {
+		// keep them for the next attempt, the bucket drops the oldest over its limit
+		s.containerEventBucket.Prepend(events)
+		log.Err(err).Msg("Failed to send container events")
+	}
+}
+
 // StartAppMetricSend sends collected app metrics
 func (s *Sender) StartAppMetricSend() {
 	go s.sendOnBucketFill()
@@ -136,7 +276,7 @@ func (s *Sender) clearOverflowingMetrics() {
 
 				if uint64(count) >= s.config.AppMetricOverflowLimit {
 					if s.internalMetrics != nil {
-						s.internalMetrics.DiscardedItems += s.config.AppMetricOverflowLimit
+						atomic.AddUint64(&s.internalMetrics.DiscardedItems, s.config.AppMetricOverflowLimit)
 					}
 
 					log.Debug().
@@ -187,7 +327,7 @@ func (s *Sender) sendOnBucketFill() {
 		select {
 		case <-s.appMetricBucket.Channel:
 			if s.internalMetrics != nil {
-				s.internalMetrics.AppMetricsReceived++
+				atomic.AddUint64(&s.internalMetrics.AppMetricsReceived, 1)
 			}
 
 			if count := s.appMetricBucket.Count(); count >= s.config.AppMetricSendCount {
@@ -212,7 +352,7 @@ func (s *Sender) sendAppMetrics(bkt *buckets.AppMetricBucket, ctx string) {
 
 	if bkt.Count() == 0 {
 		if s.internalMetrics != nil {
-			s.internalMetrics.APICallsEmpty++
+			atomic.AddUint64(&s.internalMetrics.APICallsEmpty, 1)
 		}
 
 		log.Error().
@@ -223,6 +363,7 @@ func (s *Sender) sendAppMetrics(bkt *buckets.AppMetricBucket, ctx string) {
 	}
 
 	_, err := s.api.SendAppMetrics(bkt.String())
+	s.record(StreamAppMetrics, err)
 	if err != nil {
 		go func() {
 			time.Sleep(s.config.AppMetricSleepDurationOnFailure)
@@ -237,15 +378,15 @@ func (s *Sender) sendAppMetrics(bkt *buckets.AppMetricBucket, ctx string) {
 		}()
 
 		if s.internalMetrics != nil {
-			s.internalMetrics.APICallsFail++
+			atomic.AddUint64(&s.internalMetrics.APICallsFail, 1)
 		}
 
 		return
 	}
 
 	if s.internalMetrics != nil {
-		s.internalMetrics.APICallsSuccess++
-		s.internalMetrics.AppMetricsSent += uint64(bkt.Count())
+		atomic.AddUint64(&s.internalMetrics.APICallsSuccess, 1)
+		atomic.AddUint64(&s.internalMetrics.AppMetricsSent, uint64(bkt.Count()))
 	}
 
 	s.sentAt = time.Now()
@@ -38,6 +38,14 @@ func (ac *apiClient) SendAppMetrics(data string) (*api.Response, error) {
 	return nil, nil
 }
 
+func (ac *apiClient) SendContainerEvents(data string) (*api.Response, error) {
+	if ac.returnError {
+		return nil, errors.New("error")
+	}
+
+	return nil, nil
+}
+
 // this test will fail at some point
 // refactor to mock timers...
 func TestSender_SendAppMetrics(t *testing.T) {
@@ -58,6 +66,7 @@ func TestSender_SendAppMetrics(t *testing.T) {
 		apc,
 		appBucket,
 		serverBucket,
+		buckets.NewContainerEventBucket(100),
 		cfg,
 		true,
 	)
@@ -86,6 +95,45 @@ func TestSender_SendAppMetrics(t *testing.T) {
 	spew.Dump(im)
 }
 
+func TestSender_FlushRecordsStatus(t *testing.T) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	apc := &apiClient{returnError: false}
+	appBucket := buckets.NewAppMetricBucket()
+	cfg := &monitoring.Config{
+		AppMetricSendCount:    100,
+		AppMetricSendInterval: time.Hour,
+	}
+	s := NewSender(
+		apc,
+		appBucket,
+		buckets.NewServerMetricBucket(),
+		buckets.NewContainerEventBucket(100),
+		cfg,
+		true,
+	)
+
+	fillBucket(appBucket, 250)
+	s.Flush()
+
+	assert.Equal(t, 0, appBucket.Count(), "flushed bucket")
+	assert.Equal(t, uint64(3), apc.appMetricCallsMade, "mock API calls made")
+	assert.Equal(t, uint64(250), s.GetInternalMetrics().AppMetricsSent, "bucket items sent")
+
+	status := s.Status()[StreamAppMetrics]
+	assert.Equal(t, uint64(3), status.Successes)
+	assert.NotEmpty(t, status.LastSuccessAt)
+	assert.Empty(t, status.LastError)
+
+	apc.returnError = true
+	fillBucket(appBucket, 1)
+	s.Flush()
+
+	status = s.Status()[StreamAppMetrics]
+	assert.Equal(t, uint64(1), status.Failures)
+	assert.Equal(t, "error", status.LastError)
+}
+
 func fillBucket(bucket *buckets.AppMetricBucket, limit int) {
 	for i := 0; i < limit; i++ {
 		bucket.Add(&metrics.AppMetric{})
@@ -1,4 +1,29 @@
 package server
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 // QuitMessage indicates that the agent should quit
 const QuitMessage string = "quit"
+
+// scheduleMessageKey is the top level key of messages sent on every `schedule:run`
+const scheduleMessageKey = "schedule"
+
+// IsScheduleMessage reports whether the message is a Laravel scheduler heartbeat
+func IsScheduleMessage(message string) bool {
+	// avoid decoding every request payload
+	if !strings.Contains(message, `"`+scheduleMessageKey+`"`) {
+		return false
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(message), &payload); err != nil {
+		return false
+	}
+
+	_, ok := payload[scheduleMessageKey]
+
+	return ok
+}
@@ -19,15 +19,18 @@ type DataHandler func(string)
 type Server struct {
 	socketType    string
 	socketAddress string
+	permissions   os.FileMode
 	listener      net.Listener
 	listenerStop  chan struct{}
 }
 
-// NewServer creates a new `Server` instance
-func NewServer(networkType, networkAddress string) *Server {
+// NewServer creates a new `Server` instance.
+// Unix sockets are created with `permissions` unless it's zero.
+func NewServer(networkType, networkAddress string, permissions os.FileMode) *Server {
 	return &Server{
 		socketType:    networkType,
 		socketAddress: networkAddress,
+		permissions:   permissions,
 		listenerStop:  make(chan struct{}),
 	}
 }
@@ -54,6 +57,13 @@ func (s *Server) Start(handler DataHandler) (err error) {
 		return errors.Wrapf(err, `Failed to open socket to "%s"`, s.socketAddress)
 	}
 
+	if s.socketType == "unix" && s.permissions != 0 {
+		if err := os.Chmod(s.socketAddress, s.permissions); err != nil {
+			s.listener.Close()
+			return errors.Wrapf(err, `Failed to change permissions of "%s"`, s.socketAddress)
+		}
+	}
+
 	for {
 		conn, err := s.listener.Accept()
 		if err != nil {